@@ -17,9 +17,13 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/buzzsurfr/seeder/internal/seed"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // checkCmd represents the check command
@@ -47,21 +51,37 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// checkCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	checkCmd.Flags().Bool("allow-failure", false, "exit successfully even if seeds fail")
+	viper.BindPFlag("check.allowFailure", checkCmd.Flags().Lookup("allow-failure"))
 }
 
 func check(cmd *cobra.Command, args []string) {
+	ctx, cancel := shutdownContext()
+	defer cancel()
+
 	// AWS Session
 	sess := session.Must(session.NewSessionWithOptions(session.Options{
 		SharedConfigState: session.SharedConfigEnable,
 	}))
 
 	// Load seeds from config
-	seeds := seed.UnmarshalSeeds(sess, "seeds")
-	for _, s := range seeds {
-		// Copy seeds from sources to targets
-		s.Copy()
+	seeds, err := seed.UnmarshalSeeds(sess, "seeds")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Copy seeds from sources to targets
+	results := seeds.Copy(ctx, viper.GetInt("concurrency"))
+	printResults(results)
+
+	// Close sources and targets
+	closeErr := seeds.Close()
+	if closeErr != nil {
+		fmt.Println(closeErr)
+	}
 
-		// Close source and target
-		s.Close()
+	if (results.Failed() || closeErr != nil) && !viper.GetBool("check.allowFailure") {
+		os.Exit(1)
 	}
 }
@@ -17,10 +17,14 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
+	"github.com/buzzsurfr/seeder/internal/seed"
 	"github.com/spf13/cobra"
 
 	homedir "github.com/mitchellh/go-homedir"
@@ -58,6 +62,8 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "f", "", "config file (default \"$HOME/.seeder.yaml\")")
+	rootCmd.PersistentFlags().IntP("concurrency", "c", 4, "maximum number of seeds to copy at the same time")
+	viper.BindPFlag("concurrency", rootCmd.PersistentFlags().Lookup("concurrency"))
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -97,3 +103,31 @@ func initConfig() {
 		fmt.Println("Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+// shutdownContext returns a context that is cancelled on SIGINT or SIGTERM,
+// so that in-flight copies are aborted cleanly
+func shutdownContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(sigs)
+		select {
+		case sig := <-sigs:
+			fmt.Printf("Received %s, shutting down\n", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// printResults prints the result of each seed and a summary
+func printResults(results seed.Results) {
+	for _, r := range results {
+		fmt.Println(r)
+	}
+	fmt.Println(results)
+}
@@ -0,0 +1,80 @@
+// Package cmd has the main commands for seeder
+/*
+Copyright © 2020 Theo Salvo <buzzsurfr>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/buzzsurfr/seeder/internal/seed"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validates the config file",
+	Long: `Validates the config file without contacting AWS or writing any files.
+
+Checks the apiVersion, that every source and target type is known and has its
+required fields, and that no two seeds share a name or write to the same
+target. Exits non-zero if any problem is found, for example:
+
+seeder validate -f .seeder.yaml`,
+	Run: validate,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
+
+func validate(cmd *cobra.Command, args []string) {
+	if err := viper.ReadInConfig(); err != nil {
+		fmt.Println("Unable to read config file:", err)
+		os.Exit(1)
+	}
+
+	var problems seed.Errors
+	if err := seed.ValidateAPIVersion("apiVersion"); err != nil {
+		problems = append(problems, err)
+	}
+	if err := seed.ValidateInterval("watch.interval"); err != nil {
+		problems = append(problems, err)
+	}
+	if !viper.IsSet("seeds") {
+		problems = append(problems, &seed.Error{Path: "seeds", Err: seed.ErrRequired})
+	}
+
+	cfgs, err := seed.LoadConfig("seeds")
+	var errs seed.Errors
+	switch {
+	case errors.As(err, &errs):
+		problems = append(problems, errs...)
+	case err != nil:
+		problems = append(problems, err)
+	}
+
+	if len(problems) > 0 {
+		fmt.Println(problems)
+		fmt.Printf("%d problem(s) found in %s\n", len(problems), viper.ConfigFileUsed())
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s is valid (%d seeds)\n", viper.ConfigFileUsed(), len(cfgs))
+}
@@ -17,6 +17,11 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -28,13 +33,15 @@ import (
 // watchCmd represents the watch command
 var watchCmd = &cobra.Command{
 	Use:   "watch",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+	Short: "Watches for changes to seeds",
+	Long: `Copies seeds from their sources to their targets when started, and then
+periodically. Each seed is copied at its own interval if set, or at --interval
+otherwise, plus a random delay of up to --jitter. Use --fail-fast to exit if
+any seed fails during the first copy.
+
+Send SIGHUP to refresh all seeds immediately. On SIGINT or SIGTERM, any
+in-flight copy is aborted (leaving the target as it was), all sources and
+targets are closed, and seeder exits.`,
 	Run: watch,
 }
 
@@ -52,32 +59,89 @@ func init() {
 	// watchCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	watchCmd.Flags().DurationP("interval", "n", time.Hour, "wait between updates")
 	viper.BindPFlag("watch.interval", watchCmd.Flags().Lookup("interval"))
+	watchCmd.Flags().Duration("jitter", 0, "maximum random delay added to each interval")
+	viper.BindPFlag("watch.jitter", watchCmd.Flags().Lookup("jitter"))
+	watchCmd.Flags().Bool("fail-fast", false, "exit if any seed fails during the initial sync")
+	viper.BindPFlag("watch.failFast", watchCmd.Flags().Lookup("fail-fast"))
 
 }
 
 func watch(cmd *cobra.Command, args []string) {
+	if err := seed.ValidateInterval("watch.interval"); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	interval := viper.GetDuration("watch.interval")
+
 	// AWS Session
 	sess := session.Must(session.NewSessionWithOptions(session.Options{
 		SharedConfigState: session.SharedConfigEnable,
 	}))
 
 	// Load seeds from config
-	seeds := seed.UnmarshalSeeds(sess, "seeds")
+	seeds, err := seed.UnmarshalSeeds(sess, "seeds")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
-	// Timer
-	ticker := time.NewTicker(viper.GetDuration("watch.interval"))
-	defer ticker.Stop()
+	ctx, cancel := shutdownContext()
+	defer cancel()
 
-	for {
-		select {
-		case <-ticker.C:
-			for _, s := range seeds {
-				// Copy seeds from sources to targets
-				s.Copy()
+	// SIGHUP refreshes all seeds immediately
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// Sync immediately, so that targets are available before the first interval
+	results := sync(ctx, seeds)
+	if ctx.Err() != nil {
+		closeSeeds(seeds)
+		return
+	}
+	if results.Failed() && viper.GetBool("watch.failFast") {
+		closeSeeds(seeds)
+		os.Exit(1)
+	}
 
-				// Close source and target
-				s.Close()
+	// Timer, for the next seed that is due
+	schedule := seed.NewSchedule(seeds, interval, viper.GetDuration("watch.jitter"), time.Now())
+	timer := time.NewTimer(time.Until(schedule.Next()))
+	defer timer.Stop()
+
+	for !results.Missing() {
+		select {
+		case <-ctx.Done():
+			closeSeeds(seeds)
+			return
+		case <-hup:
+			fmt.Println("Received hangup, refreshing seeds")
+			results = sync(ctx, seeds)
+			schedule.Reset(time.Now())
+			if !timer.Stop() {
+				<-timer.C
 			}
+		case <-timer.C:
+			results = sync(ctx, schedule.Due(time.Now()))
 		}
+		timer.Reset(time.Until(schedule.Next()))
+	}
+
+	// Stop when a required seed no longer exists
+	closeSeeds(seeds)
+	os.Exit(1)
+}
+
+// sync copies seeds from sources to targets
+func sync(ctx context.Context, seeds seed.Seeds) seed.Results {
+	results := seeds.Copy(ctx, viper.GetInt("concurrency"))
+	printResults(results)
+	return results
+}
+
+// closeSeeds closes sources and targets
+func closeSeeds(seeds seed.Seeds) {
+	if err := seeds.Close(); err != nil {
+		fmt.Println(err)
 	}
 }
@@ -5,9 +5,11 @@ go 1.15
 require (
 	github.com/aws/aws-sdk-go v1.35.26
 	github.com/mitchellh/go-homedir v1.1.0
+	github.com/mitchellh/mapstructure v1.1.2
 	github.com/spf13/cobra v1.1.1
 	github.com/spf13/viper v1.7.1
 	go.hein.dev/go-version v0.1.0
+	golang.org/x/crypto v0.0.0-20190701094942-4def268fd1a4
 	gopkg.in/yaml.v2 v2.3.0 // indirect
 	sigs.k8s.io/yaml v1.2.0 // indirect
 )
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+var (
+	// ErrNotFound is the error of a source that does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrRetryable is the error of a source that failed temporarily, for
+	// example when throttled, and may succeed if tried again
+	ErrRetryable = errors.New("temporary failure")
+)
+
+// markedError marks an error as another error, while keeping its message
+type markedError struct {
+	err  error
+	mark error
+}
+
+func (e *markedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *markedError) Unwrap() error {
+	return e.err
+}
+
+func (e *markedError) Is(target error) bool {
+	return target == e.mark
+}
+
+// NotFound marks err as the error of a source that does not exist, so that
+// errors.Is(err, ErrNotFound) is true
+func NotFound(err error) error {
+	return &markedError{err: err, mark: ErrNotFound}
+}
+
+// Retryable marks err as a temporary failure, so that
+// errors.Is(err, ErrRetryable) is true
+func Retryable(err error) error {
+	return &markedError{err: err, mark: ErrRetryable}
+}
+
+// ClassifyAWS marks err as a temporary failure if cause, the error of an AWS
+// request, is throttling or otherwise retryable. Otherwise err is returned as
+// is.
+func ClassifyAWS(cause, err error) error {
+	if request.IsErrorThrottle(cause) || request.IsErrorRetryable(cause) {
+		return Retryable(err)
+	}
+	return err
+}
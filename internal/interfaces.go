@@ -1,11 +1,64 @@
 package internal
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
+// Source is where the content of a seed comes from. Fetch gets the latest
+// content, which is then returned by Read. Cancelling the context aborts the
+// fetch; the fetched content is held in memory, so reads are not cancellable.
 type Source interface {
 	io.ReadCloser
+	Fetch(ctx context.Context) error
 }
 
+// Target is where the content of a seed is written to
 type Target interface {
 	io.WriteCloser
 }
+
+// Secret is implemented by sources that know whether their value is secret,
+// so that targets can protect it
+type Secret interface {
+	IsSecret() bool
+}
+
+// Changer is implemented by targets that know whether the last write changed
+// their content
+type Changer interface {
+	Changed() bool
+}
+
+// Versioner is implemented by sources that know the version of their content,
+// so that the version written can be reported
+type Versioner interface {
+	Version() string
+}
+
+// Entry is a single value of a Tree, named by its path relative to the tree
+type Entry struct {
+	Name   string
+	Value  []byte
+	Secret bool
+}
+
+// Tree is implemented by sources with many values (such as a hierarchy of
+// parameters), which are written as separate entries rather than read
+type Tree interface {
+	Source
+	Entries() []Entry
+}
+
+// Pruner is implemented by trees that choose whether entries that are no
+// longer in the tree are removed from the target. Other trees always are.
+type Pruner interface {
+	Prune() bool
+}
+
+// TreeWriter is implemented by targets that can write all entries of a Tree.
+// With prune, entries that were written before but are no longer in the tree
+// are removed.
+type TreeWriter interface {
+	WriteTree(entries []Entry, prune bool) (int64, error)
+}
@@ -0,0 +1,719 @@
+package seed
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buzzsurfr/seeder/internal/sources/aws/kms"
+	"github.com/buzzsurfr/seeder/internal/sources/aws/s3"
+	"github.com/buzzsurfr/seeder/internal/sources/aws/secretsmanager"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// APIVersion is the version of the configuration file supported by seeder
+const APIVersion = "v1alpha1"
+
+// ErrRequired is an error when a required field is missing
+var ErrRequired = errors.New("required field is missing")
+
+// ValidateAPIVersion reads a key from viper and checks that it contains a
+// supported apiVersion
+func ValidateAPIVersion(key string) error {
+	switch v := viper.GetString(key); v {
+	case APIVersion:
+		return nil
+	case "":
+		return &Error{Path: key, Err: ErrRequired}
+	default:
+		return &Error{Path: key, Err: fmt.Errorf("unsupported version %q, expected %q", v, APIVersion)}
+	}
+}
+
+// ValidateInterval reads a key from viper and checks that it is an interval of
+// at least MinInterval
+func ValidateInterval(key string) error {
+	if err := validateInterval(viper.GetDuration(key)); err != nil {
+		return &Error{Path: key, Err: err}
+	}
+	return nil
+}
+
+// validateInterval checks that an interval is at least MinInterval. Bare
+// numbers are decoded as nanoseconds, so the error suggests a unit.
+func validateInterval(d time.Duration) error {
+	if d < MinInterval {
+		return fmt.Errorf("must be at least %s, got %s (use a duration such as \"60s\")", MinInterval, d)
+	}
+	return nil
+}
+
+// Config is the configuration of a single seed
+type Config struct {
+	Name      string                 `mapstructure:"name"`
+	Optional  bool                   `mapstructure:"optional"`
+	Interval  time.Duration          `mapstructure:"interval"`
+	Retry     map[string]interface{} `mapstructure:"retry"`
+	DependsOn []string               `mapstructure:"dependsOn"`
+	Source    SourceConfig           `mapstructure:"source"`
+	Target    TargetConfig           `mapstructure:"target"`
+
+	retry RetryPolicy
+}
+
+// SourceConfig is the configuration of the source of a seed
+type SourceConfig struct {
+	Type string                 `mapstructure:"type"`
+	Spec map[string]interface{} `mapstructure:"spec"`
+
+	spec interface{}
+}
+
+// TargetConfig is the configuration of the target of a seed
+type TargetConfig struct {
+	Type string                 `mapstructure:"type"`
+	Spec map[string]interface{} `mapstructure:"spec"`
+
+	spec interface{}
+}
+
+// SSMParameterSpec is the spec of a ssm-parameter source
+type SSMParameterSpec struct {
+	AWSSpec `mapstructure:",squash"`
+
+	Name    string `mapstructure:"name"`
+	Version int64  `mapstructure:"version"`
+	Label   string `mapstructure:"label"`
+}
+
+// SSMPathSpec is the spec of a ssm-path source
+type SSMPathSpec struct {
+	AWSSpec `mapstructure:",squash"`
+
+	Path string `mapstructure:"path"`
+}
+
+// SecretsManagerSpec is the spec of a secretsmanager source
+type SecretsManagerSpec struct {
+	AWSSpec `mapstructure:",squash"`
+
+	SecretID     string `mapstructure:"secretId"`
+	VersionStage string `mapstructure:"versionStage"`
+	VersionID    string `mapstructure:"versionId"`
+	Encoding     string `mapstructure:"encoding"`
+	Key          string `mapstructure:"key"`
+	Explode      bool   `mapstructure:"explode"`
+}
+
+// S3ObjectSpec is the spec of a s3-object source
+type S3ObjectSpec struct {
+	AWSSpec `mapstructure:",squash"`
+
+	URI            string `mapstructure:"uri"`
+	Bucket         string `mapstructure:"bucket"`
+	Key            string `mapstructure:"key"`
+	VersionID      string `mapstructure:"versionId"`
+	ForcePathStyle bool   `mapstructure:"forcePathStyle"`
+}
+
+// S3PrefixSpec is the spec of a s3-prefix source
+type S3PrefixSpec struct {
+	AWSSpec `mapstructure:",squash"`
+
+	URI            string   `mapstructure:"uri"`
+	Bucket         string   `mapstructure:"bucket"`
+	Prefix         string   `mapstructure:"prefix"`
+	Include        []string `mapstructure:"include"`
+	Exclude        []string `mapstructure:"exclude"`
+	Delete         bool     `mapstructure:"delete"`
+	ForcePathStyle bool     `mapstructure:"forcePathStyle"`
+}
+
+// ACMCertificateSpec is the spec of an acm-certificate source. The passphrase
+// to export the certificate with is taken from the source of the seed named by
+// PassphraseFrom, or generated.
+type ACMCertificateSpec struct {
+	AWSSpec `mapstructure:",squash"`
+
+	CertificateARN string `mapstructure:"certificateArn"`
+	PassphraseFrom string `mapstructure:"passphraseFrom"`
+}
+
+// KMSDecryptSpec is the spec of a kms-decrypt source. The ciphertext is read
+// from the nested Source, or is the base64-encoded Ciphertext.
+type KMSDecryptSpec struct {
+	AWSSpec `mapstructure:",squash"`
+
+	Source            *SourceConfig     `mapstructure:"source"`
+	Ciphertext        string            `mapstructure:"ciphertext"`
+	EncryptionContext map[string]string `mapstructure:"encryptionContext"`
+	KeyID             string            `mapstructure:"keyId"`
+	Encoding          string            `mapstructure:"encoding"`
+}
+
+// FileSourceSpec is the spec of a file source
+type FileSourceSpec struct {
+	Path string `mapstructure:"path"`
+}
+
+// FileSpec is the spec of a file target
+type FileSpec struct {
+	Path    string      `mapstructure:"path"`
+	Name    string      `mapstructure:"name"`
+	Mode    os.FileMode `mapstructure:"mode"`
+	DirMode os.FileMode `mapstructure:"dirMode"`
+	UID     *int        `mapstructure:"uid"`
+	GID     *int        `mapstructure:"gid"`
+	Owner   string      `mapstructure:"owner"`
+	Group   string      `mapstructure:"group"`
+}
+
+// Error is a problem with the configuration of a seed
+type Error struct {
+	Seed string
+	Path string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Seed == "" {
+		return fmt.Sprintf("%s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s (seed %q): %v", e.Path, e.Seed, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errors is a collection of configuration errors
+type Errors []error
+
+func (errs Errors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// errorList collects the errors of a single seed
+type errorList struct {
+	seed string
+	path string
+	errs Errors
+}
+
+func (l *errorList) add(field string, err error) {
+	path := l.path
+	if field != "" {
+		path += "." + field
+	}
+	l.errs = append(l.errs, &Error{Seed: l.seed, Path: path, Err: err})
+}
+
+func (l *errorList) addf(field, format string, a ...interface{}) {
+	l.add(field, fmt.Errorf(format, a...))
+}
+
+// sub returns an empty list for the errors of a field, to be added to l
+func (l *errorList) sub(field string) *errorList {
+	return &errorList{seed: l.seed, path: joinField(l.path, field)}
+}
+
+// decode decodes input into output, reporting unknown and malformed fields
+// relative to field
+func (l *errorList) decode(field string, input, output interface{}) {
+	var md mapstructure.Metadata
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.ComposeDecodeHookFunc(fileModeHook, mapstructure.StringToTimeDurationHookFunc()),
+		Metadata:         &md,
+		Result:           output,
+		WeaklyTypedInput: true,
+	})
+	if err != nil {
+		l.add(field, err)
+		return
+	}
+
+	if err := decoder.Decode(input); err != nil {
+		var merr *mapstructure.Error
+		if errors.As(err, &merr) {
+			for _, e := range merr.Errors {
+				l.add(field, errors.New(e))
+			}
+		} else {
+			l.add(field, err)
+		}
+	}
+
+	sort.Strings(md.Unused)
+	for _, key := range md.Unused {
+		l.add(joinField(field, key), errors.New("unknown field"))
+	}
+}
+
+// fileModeHook decodes octal strings such as "0600" into an os.FileMode.
+// Unquoted octal numbers are already decoded by YAML.
+func fileModeHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(os.FileMode(0)) || from.Kind() != reflect.String {
+		return data, nil
+	}
+	mode, err := strconv.ParseUint(data.(string), 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file mode %q", data)
+	}
+	return os.FileMode(mode), nil
+}
+
+func joinField(field, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + "." + key
+}
+
+// LoadConfig reads a key from viper and returns the configuration of every
+// seed. All problems found are returned together as Errors.
+//
+// The retry policy of every seed defaults to the "retry" key, or
+// DefaultRetryPolicy.
+func LoadConfig(key string) ([]Config, error) {
+	var errs Errors
+
+	retry := DefaultRetryPolicy
+	rl := errorList{path: "retry"}
+	rl.decode("", viper.Get("retry"), &retry)
+	retry.validate(&rl, "")
+	errs = append(errs, rl.errs...)
+
+	raw := viper.Get(key)
+	if raw == nil {
+		if len(errs) > 0 {
+			return nil, errs
+		}
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, Errors{&Error{Path: key, Err: fmt.Errorf("expected a list of seeds, got %T", raw)}}
+	}
+
+	cfgs := make([]Config, 0, len(items))
+	names := make(map[string]string)
+	var destinations []destination
+	for i, item := range items {
+		l := errorList{path: fmt.Sprintf("%s[%d]", key, i)}
+		if m, ok := item.(map[interface{}]interface{}); ok {
+			if name, ok := m["name"].(string); ok {
+				l.seed = name
+			}
+		}
+
+		cfg := Config{retry: retry}
+		l.decode("", item, &cfg)
+		cfg.validate(&l)
+
+		// Seeds must be unique, and must not overwrite each other
+		if cfg.Name != "" {
+			if other, ok := names[cfg.Name]; ok {
+				l.addf("name", "duplicate seed name, also used by %s", other)
+			} else {
+				names[cfg.Name] = l.path
+			}
+		}
+		if dest := cfg.Target.destination(); dest != "" {
+			tree := cfg.Source.tree()
+			for _, other := range destinations {
+				if dest == other.path {
+					l.addf("target", "duplicate target %s, also used by %s", dest, other.seed)
+					break
+				}
+				// Trees write whole directories, which must not overlap
+				if (tree || other.tree) && (within(dest, other.path) || within(other.path, dest)) {
+					l.addf("target", "target %s overlaps %s, used by %s", dest, other.path, other.seed)
+					break
+				}
+			}
+			destinations = append(destinations, destination{path: dest, seed: l.path, tree: tree})
+		}
+
+		errs = append(errs, l.errs...)
+		cfgs = append(cfgs, cfg)
+	}
+	errs = append(errs, validateDependencies(key, cfgs)...)
+	errs = append(errs, validatePassphrases(key, cfgs)...)
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return cfgs, nil
+}
+
+// validateDependencies checks that seeds only depend on other seeds, and that
+// there are no dependency cycles
+func validateDependencies(key string, cfgs []Config) Errors {
+	var errs Errors
+
+	index := make(map[string]int, len(cfgs))
+	for i, cfg := range cfgs {
+		if _, ok := index[cfg.Name]; !ok {
+			index[cfg.Name] = i
+		}
+	}
+	for i, cfg := range cfgs {
+		for j, dep := range cfg.DependsOn {
+			path := fmt.Sprintf("%s[%d].dependsOn[%d]", key, i, j)
+			if _, ok := index[dep]; !ok {
+				errs = append(errs, &Error{Seed: cfg.Name, Path: path, Err: fmt.Errorf("unknown seed %q", dep)})
+			} else if dep == cfg.Name {
+				errs = append(errs, &Error{Seed: cfg.Name, Path: path, Err: errors.New("seed cannot depend on itself")})
+			}
+		}
+	}
+
+	// Depth-first search, where reaching a seed that is still being visited
+	// means there is a cycle
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(cfgs))
+	var stack []int
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, dep := range cfgs[i].DependsOn {
+			j, ok := index[dep]
+			if !ok || j == i {
+				continue
+			}
+			switch state[j] {
+			case unvisited:
+				visit(j)
+			case visiting:
+				var cycle []string
+				for k := len(stack) - 1; k >= 0; k-- {
+					if stack[k] == j {
+						for _, n := range stack[k:] {
+							cycle = append(cycle, cfgs[n].Name)
+						}
+						break
+					}
+				}
+				cycle = append(cycle, cfgs[j].Name)
+				errs = append(errs, &Error{
+					Seed: cfgs[j].Name,
+					Path: fmt.Sprintf("%s[%d].dependsOn", key, j),
+					Err:  fmt.Errorf("dependency cycle %s", strings.Join(cycle, " -> ")),
+				})
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+	}
+	for i := range cfgs {
+		if state[i] == unvisited {
+			visit(i)
+		}
+	}
+
+	return errs
+}
+
+// validatePassphrases checks that passphrases are taken from seeds with a
+// single value
+func validatePassphrases(key string, cfgs []Config) Errors {
+	var errs Errors
+
+	index := make(map[string]int, len(cfgs))
+	for i, cfg := range cfgs {
+		if _, ok := index[cfg.Name]; !ok {
+			index[cfg.Name] = i
+		}
+	}
+	for i, cfg := range cfgs {
+		spec, ok := cfg.Source.spec.(*ACMCertificateSpec)
+		if !ok || spec.PassphraseFrom == "" {
+			continue
+		}
+		path := fmt.Sprintf("%s[%d].source.spec.passphraseFrom", key, i)
+		j, ok := index[spec.PassphraseFrom]
+		switch {
+		case !ok:
+			errs = append(errs, &Error{Seed: cfg.Name, Path: path, Err: fmt.Errorf("unknown seed %q", spec.PassphraseFrom)})
+		case cfgs[j].Source.spec == nil:
+			// Invalid source, which is reported already
+		case cfgs[j].Source.tree():
+			errs = append(errs, &Error{Seed: cfg.Name, Path: path, Err: fmt.Errorf("seed %q has many values", spec.PassphraseFrom)})
+		}
+	}
+
+	return errs
+}
+
+func (cfg *Config) validate(l *errorList) {
+	if cfg.Name == "" {
+		l.add("name", ErrRequired)
+	}
+	if cfg.Interval != 0 {
+		if err := validateInterval(cfg.Interval); err != nil {
+			l.add("interval", err)
+		}
+	}
+	if cfg.Retry != nil {
+		l.decode("retry", cfg.Retry, &cfg.retry)
+		cfg.retry.validate(l, "retry")
+	}
+
+	// Source
+	sl := l.sub("source")
+	cfg.Source.validate(sl)
+	l.errs = append(l.errs, sl.errs...)
+
+	// Target
+	switch cfg.Target.Type {
+	case "":
+		l.add("target.type", ErrRequired)
+	case "file":
+		spec := FileSpec{
+			Path: viper.GetString("default.target.path"),
+		}
+		l.decode("target.spec", cfg.Target.Spec, &spec)
+		if spec.Path == "" {
+			l.add("target.spec.path", ErrRequired)
+		}
+		if spec.Name == "" && !cfg.Source.tree() {
+			l.add("target.spec.name", ErrRequired)
+		}
+		if spec.Mode&^os.ModePerm != 0 {
+			l.addf("target.spec.mode", "invalid file mode %#o", spec.Mode)
+		}
+		if spec.DirMode&^os.ModePerm != 0 {
+			l.addf("target.spec.dirMode", "invalid file mode %#o", spec.DirMode)
+		}
+		if spec.UID != nil && spec.Owner != "" {
+			l.add("target.spec.owner", errors.New("cannot be combined with uid"))
+		}
+		if spec.GID != nil && spec.Group != "" {
+			l.add("target.spec.group", errors.New("cannot be combined with gid"))
+		}
+		cfg.Target.spec = &spec
+	default:
+		l.addf("target.type", "unknown target type %q", cfg.Target.Type)
+	}
+}
+
+// tree reports whether the source has many entries, which are written to a
+// directory rather than a single file
+func (cfg *SourceConfig) tree() bool {
+	switch spec := cfg.spec.(type) {
+	case *SSMPathSpec, *S3PrefixSpec:
+		return true
+	case *SecretsManagerSpec:
+		return spec.Explode
+	case *ACMCertificateSpec:
+		return true
+	}
+	return false
+}
+
+// validate checks the type and spec of a source
+func (cfg *SourceConfig) validate(l *errorList) {
+	switch cfg.Type {
+	case "":
+		l.add("type", ErrRequired)
+	case "ssm-parameter":
+		spec := SSMParameterSpec{}
+		l.decode("spec", cfg.Spec, &spec)
+		if spec.Name == "" {
+			l.add("spec.name", ErrRequired)
+		}
+		switch {
+		case spec.Version != 0 && spec.Label != "":
+			l.add("spec.label", errors.New("cannot be combined with version"))
+		case (spec.Version != 0 || spec.Label != "") && strings.Contains(spec.Name, ":"):
+			l.add("spec.name", errors.New("cannot have a selector when version or label is set"))
+		case spec.Version < 0:
+			l.add("spec.version", errors.New("must be at least 1"))
+		}
+		cfg.spec = &spec
+	case "ssm-path":
+		spec := SSMPathSpec{}
+		l.decode("spec", cfg.Spec, &spec)
+		switch {
+		case spec.Path == "":
+			l.add("spec.path", ErrRequired)
+		case !strings.HasPrefix(spec.Path, "/"):
+			l.add("spec.path", errors.New("must start with /"))
+		}
+		cfg.spec = &spec
+	case "secretsmanager":
+		spec := SecretsManagerSpec{}
+		l.decode("spec", cfg.Spec, &spec)
+		if spec.SecretID == "" {
+			l.add("spec.secretId", ErrRequired)
+		}
+		switch spec.Encoding {
+		case "", secretsmanager.EncodingBase64:
+		default:
+			l.addf("spec.encoding", "unknown encoding %q, expected %q", spec.Encoding, secretsmanager.EncodingBase64)
+		}
+		if spec.Key != "" {
+			if err := secretsmanager.ValidateKey(spec.Key); err != nil {
+				l.add("spec.key", err)
+			}
+		}
+		cfg.spec = &spec
+	case "s3-object":
+		spec := S3ObjectSpec{}
+		l.decode("spec", cfg.Spec, &spec)
+		switch {
+		case spec.URI != "" && (spec.Bucket != "" || spec.Key != ""):
+			l.add("spec.uri", errors.New("cannot be combined with bucket and key"))
+		case spec.URI != "":
+			u, err := spec.parseURI()
+			switch {
+			case err != nil:
+				l.add("spec.uri", err)
+			case u.Key == nil:
+				l.add("spec.uri", errors.New("key could not be found"))
+			}
+		default:
+			if spec.Bucket == "" {
+				l.add("spec.bucket", ErrRequired)
+			}
+			if spec.Key == "" {
+				l.add("spec.key", ErrRequired)
+			}
+		}
+		cfg.spec = &spec
+	case "s3-prefix":
+		spec := S3PrefixSpec{}
+		l.decode("spec", cfg.Spec, &spec)
+		switch {
+		case spec.URI != "" && (spec.Bucket != "" || spec.Prefix != ""):
+			l.add("spec.uri", errors.New("cannot be combined with bucket and prefix"))
+		case spec.URI != "":
+			if _, err := spec.parseURI(); err != nil {
+				l.add("spec.uri", err)
+			}
+		case spec.Bucket == "":
+			l.add("spec.bucket", ErrRequired)
+		}
+		for i, pattern := range spec.Include {
+			if _, err := s3.MatchPattern(pattern, ""); err != nil {
+				l.addf(fmt.Sprintf("spec.include[%d]", i), "invalid pattern %q", pattern)
+			}
+		}
+		for i, pattern := range spec.Exclude {
+			if _, err := s3.MatchPattern(pattern, ""); err != nil {
+				l.addf(fmt.Sprintf("spec.exclude[%d]", i), "invalid pattern %q", pattern)
+			}
+		}
+		cfg.spec = &spec
+	case "acm-certificate":
+		spec := ACMCertificateSpec{}
+		l.decode("spec", cfg.Spec, &spec)
+		switch {
+		case spec.CertificateARN == "":
+			l.add("spec.certificateArn", ErrRequired)
+		case !strings.HasPrefix(spec.CertificateARN, "arn:"):
+			l.add("spec.certificateArn", errors.New("must be an ARN"))
+		}
+		if spec.PassphraseFrom != "" && spec.PassphraseFrom == l.seed {
+			l.add("spec.passphraseFrom", errors.New("seed cannot take its own passphrase"))
+		}
+		cfg.spec = &spec
+	case "kms-decrypt":
+		spec := KMSDecryptSpec{}
+		l.decode("spec", cfg.Spec, &spec)
+		switch {
+		case spec.Source != nil && spec.Ciphertext != "":
+			l.add("spec.ciphertext", errors.New("cannot be combined with source"))
+		case spec.Source != nil:
+			sl := l.sub("spec.source")
+			spec.Source.validate(sl)
+			l.errs = append(l.errs, sl.errs...)
+			if spec.Source.tree() {
+				l.add("spec.source", errors.New("must have a single value"))
+			}
+		case spec.Ciphertext != "":
+			if _, err := base64.StdEncoding.DecodeString(spec.Ciphertext); err != nil {
+				l.add("spec.ciphertext", fmt.Errorf("invalid base64: %w", err))
+			}
+			if spec.Encoding != "" {
+				l.add("spec.encoding", errors.New("cannot be combined with ciphertext, which is always base64"))
+			}
+		default:
+			l.add("spec.source", ErrRequired)
+		}
+		switch spec.Encoding {
+		case "", kms.EncodingBase64:
+		default:
+			l.addf("spec.encoding", "unknown encoding %q, expected %q", spec.Encoding, kms.EncodingBase64)
+		}
+		cfg.spec = &spec
+	case "file":
+		spec := FileSourceSpec{}
+		l.decode("spec", cfg.Spec, &spec)
+		if spec.Path == "" {
+			l.add("spec.path", ErrRequired)
+		}
+		cfg.spec = &spec
+	default:
+		l.addf("type", "unknown source type %q", cfg.Type)
+	}
+
+	if spec, ok := cfg.spec.(awsSpecer); ok {
+		spec.awsSpec().validate(l, "spec")
+	}
+}
+
+// parseURI parses the URI of the spec, accepting URLs of its endpoint
+func (spec *S3ObjectSpec) parseURI() (*s3.URI, error) {
+	return s3.NewURI(s3.WithEndpoint(spec.endpoint())).ParseString(spec.URI)
+}
+
+// parseURI parses the URI of the spec, with the prefix as the key
+func (spec *S3PrefixSpec) parseURI() (*s3.URI, error) {
+	return s3.NewURI(s3.WithEndpoint(spec.endpoint()), s3.WithNormalizedKey(true)).ParseString(spec.URI)
+}
+
+// destination is where the target of a seed writes to. The target of a tree
+// writes to everything within the directory.
+type destination struct {
+	path string
+	seed string
+	tree bool
+}
+
+// within reports whether path is dir, or a path within dir
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// destination identifies where a target writes to, so that seeds writing to
+// the same place can be detected
+func (cfg *TargetConfig) destination() string {
+	switch spec := cfg.spec.(type) {
+	case *FileSpec:
+		if spec.Path == "" {
+			return ""
+		}
+		return filepath.Join(spec.Path, spec.Name)
+	}
+	return ""
+}
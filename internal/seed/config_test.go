@@ -0,0 +1,279 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// readConfig replaces the configuration of viper with a YAML document
+func readConfig(t *testing.T, yaml string) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader(yaml)); err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+}
+
+// errorStrings returns the message of every error of LoadConfig
+func errorStrings(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error = %v (%T), want Errors", err, err)
+	}
+	s := make([]string, len(errs))
+	for i, e := range errs {
+		s[i] = e.Error()
+	}
+	return s
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "valid",
+			yaml: `
+seeds:
+- name: a
+  source: {type: ssm-parameter, spec: {name: /a}}
+  target: {type: file, spec: {path: /tmp/seeds, name: a}}
+- name: b
+  dependsOn: [a]
+  source: {type: ssm-path, spec: {path: /b}}
+  target: {type: file, spec: {path: /tmp/b}}
+`,
+		},
+		{
+			name: "no seeds",
+			yaml: `apiVersion: v1alpha1`,
+		},
+		{
+			name: "invalid retry without seeds",
+			yaml: `retry: {maxAttempts: 0}`,
+			want: []string{"retry.maxAttempts: must be at least 1"},
+		},
+		{
+			name: "seeds is not a list",
+			yaml: `seeds: {name: a}`,
+			want: []string{"seeds: expected a list of seeds, got map[string]interface {}"},
+		},
+		{
+			name: "every problem of every seed",
+			yaml: `
+seeds:
+- source: {type: ssm-parameter, spec: {bogus: true}}
+  target: {type: file, spec: {path: /tmp/seeds, name: a}}
+- name: b
+  source: {type: nope}
+  target: {type: file, spec: {name: b}}
+`,
+			want: []string{
+				"seeds[0].name: required field is missing",
+				"seeds[0].source.spec.bogus: unknown field",
+				"seeds[0].source.spec.name: required field is missing",
+				`seeds[1].source.type (seed "b"): unknown source type "nope"`,
+				`seeds[1].target.spec.path (seed "b"): required field is missing`,
+			},
+		},
+		{
+			name: "duplicate names and targets",
+			yaml: `
+seeds:
+- name: a
+  source: {type: ssm-parameter, spec: {name: /a}}
+  target: {type: file, spec: {path: /tmp/seeds, name: a}}
+- name: a
+  source: {type: ssm-parameter, spec: {name: /b}}
+  target: {type: file, spec: {path: /tmp/seeds, name: a}}
+`,
+			want: []string{
+				`seeds[1].name (seed "a"): duplicate seed name, also used by seeds[0]`,
+				`seeds[1].target (seed "a"): duplicate target /tmp/seeds/a, also used by seeds[0]`,
+			},
+		},
+		{
+			name: "overlapping trees",
+			yaml: `
+seeds:
+- name: app
+  source: {type: ssm-path, spec: {path: /app}}
+  target: {type: file, spec: {path: /etc/app}}
+- name: db
+  source: {type: secretsmanager, spec: {secretId: db, explode: true}}
+  target: {type: file, spec: {path: /etc/app/db}}
+`,
+			want: []string{
+				`seeds[1].target (seed "db"): target /etc/app/db overlaps /etc/app, used by seeds[0]`,
+			},
+		},
+		{
+			name: "retry",
+			yaml: `
+seeds:
+- name: a
+  retry: {maxAttempts: 0, bogus: 1}
+  source: {type: ssm-parameter, spec: {name: /a}}
+  target: {type: file, spec: {path: /tmp/seeds, name: a}}
+`,
+			want: []string{
+				`seeds[0].retry.bogus (seed "a"): unknown field`,
+				`seeds[0].retry.maxAttempts (seed "a"): must be at least 1`,
+			},
+		},
+		{
+			name: "intervals",
+			yaml: `
+seeds:
+- name: a
+  interval: 1m
+  source: {type: ssm-parameter, spec: {name: /a}}
+  target: {type: file, spec: {path: /tmp/seeds, name: a}}
+- name: b
+  interval: 60
+  source: {type: ssm-parameter, spec: {name: /b}}
+  target: {type: file, spec: {path: /tmp/seeds, name: b}}
+- name: c
+  interval: -1s
+  source: {type: ssm-parameter, spec: {name: /c}}
+  target: {type: file, spec: {path: /tmp/seeds, name: c}}
+`,
+			want: []string{
+				`seeds[1].interval (seed "b"): must be at least 1s, got 60ns (use a duration such as "60s")`,
+				`seeds[2].interval (seed "c"): must be at least 1s, got -1s (use a duration such as "60s")`,
+			},
+		},
+		{
+			name: "unknown and own dependencies",
+			yaml: `
+seeds:
+- name: a
+  dependsOn: [a, z]
+  source: {type: ssm-parameter, spec: {name: /a}}
+  target: {type: file, spec: {path: /tmp/seeds, name: a}}
+`,
+			want: []string{
+				`seeds[0].dependsOn[0] (seed "a"): seed cannot depend on itself`,
+				`seeds[0].dependsOn[1] (seed "a"): unknown seed "z"`,
+			},
+		},
+		{
+			name: "dependency cycle",
+			yaml: `
+seeds:
+- name: a
+  dependsOn: [c]
+  source: {type: ssm-parameter, spec: {name: /a}}
+  target: {type: file, spec: {path: /tmp/seeds, name: a}}
+- name: b
+  dependsOn: [a]
+  source: {type: ssm-parameter, spec: {name: /b}}
+  target: {type: file, spec: {path: /tmp/seeds, name: b}}
+- name: c
+  dependsOn: [b]
+  source: {type: ssm-parameter, spec: {name: /c}}
+  target: {type: file, spec: {path: /tmp/seeds, name: c}}
+`,
+			want: []string{
+				`seeds[0].dependsOn (seed "a"): dependency cycle a -> c -> b -> a`,
+			},
+		},
+		{
+			name: "passphrases",
+			yaml: `
+seeds:
+- name: cert
+  source: {type: acm-certificate, spec: {certificateArn: "arn:aws:acm:us-east-1:123456789012:certificate/1", passphraseFrom: app}}
+  target: {type: file, spec: {path: /etc/cert}}
+- name: other
+  source: {type: acm-certificate, spec: {certificateArn: "arn:aws:acm:us-east-1:123456789012:certificate/2", passphraseFrom: missing}}
+  target: {type: file, spec: {path: /etc/other}}
+- name: app
+  source: {type: ssm-path, spec: {path: /app}}
+  target: {type: file, spec: {path: /etc/app}}
+`,
+			want: []string{
+				`seeds[0].source.spec.passphraseFrom (seed "cert"): seed "app" has many values`,
+				`seeds[1].source.spec.passphraseFrom (seed "other"): unknown seed "missing"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readConfig(t, tt.yaml)
+			_, err := LoadConfig("seeds")
+			got := errorStrings(t, err)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("LoadConfig() errors =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	readConfig(t, `
+retry: {maxAttempts: 5}
+default:
+  target:
+    path: /etc/seeds
+seeds:
+- name: a
+  source: {type: ssm-parameter, spec: {name: /a, region: eu-west-1}}
+  target: {type: file, spec: {name: a}}
+- name: b
+  retry: {maxAttempts: 1}
+  source: {type: ssm-parameter, spec: {name: /b}}
+  target: {type: file, spec: {name: b}}
+`)
+	cfgs, err := LoadConfig("seeds")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(cfgs) != 2 {
+		t.Fatalf("LoadConfig() = %d seeds, want 2", len(cfgs))
+	}
+
+	spec, ok := cfgs[0].Source.spec.(*SSMParameterSpec)
+	if !ok || spec.Name != "/a" || spec.Region != "eu-west-1" {
+		t.Errorf("source spec = %#v, want name /a in eu-west-1", cfgs[0].Source.spec)
+	}
+	if target, ok := cfgs[0].Target.spec.(*FileSpec); !ok || target.Path != "/etc/seeds" {
+		t.Errorf("target spec = %#v, want the default path", cfgs[0].Target.spec)
+	}
+	if cfgs[0].retry.MaxAttempts != 5 || cfgs[0].retry.BaseDelay != DefaultRetryPolicy.BaseDelay {
+		t.Errorf("retry = %+v, want the global policy", cfgs[0].retry)
+	}
+	if cfgs[1].retry.MaxAttempts != 1 {
+		t.Errorf("retry = %+v, want the policy of the seed", cfgs[1].retry)
+	}
+}
+
+func TestValidateInterval(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want string
+	}{
+		{yaml: `watch: {interval: 5m}`},
+		{yaml: `watch: {interval: 300}`, want: `watch.interval: must be at least 1s, got 300ns (use a duration such as "60s")`},
+		{yaml: `watch: {interval: 0s}`, want: `watch.interval: must be at least 1s, got 0s (use a duration such as "60s")`},
+	}
+	for _, tt := range tests {
+		readConfig(t, tt.yaml)
+		err := ValidateInterval("watch.interval")
+		if got := fmt.Sprint(err); (err != nil || tt.want != "") && got != tt.want {
+			t.Errorf("ValidateInterval() with %s = %v, want %s", tt.yaml, err, tt.want)
+		}
+	}
+}
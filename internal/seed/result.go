@@ -0,0 +1,104 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// Status is the outcome of copying a seed
+type Status string
+
+const (
+	// StatusUpdated means the target was written
+	StatusUpdated Status = "updated"
+	// StatusUnchanged means the target already had the content of the source
+	StatusUnchanged Status = "unchanged"
+	// StatusSkipped means the source of an optional seed does not exist
+	StatusSkipped Status = "skipped"
+	// StatusFailed means the seed could not be copied
+	StatusFailed Status = "failed"
+)
+
+// Result is the result of copying a seed
+type Result struct {
+	Name     string
+	Status   Status
+	Written  int64
+	Version  string
+	Attempts int
+	Err      error
+}
+
+// Missing reports whether the seed failed because its source does not exist
+func (r Result) Missing() bool {
+	return r.Status == StatusFailed && errors.Is(r.Err, internal.ErrNotFound)
+}
+
+func (r Result) String() string {
+	switch r.Status {
+	case StatusFailed, StatusSkipped:
+		if r.Attempts > 1 {
+			return fmt.Sprintf("%s: %s after %d attempts: %v", r.Name, r.Status, r.Attempts, r.Err)
+		}
+		return fmt.Sprintf("%s: %s: %v", r.Name, r.Status, r.Err)
+	case StatusUpdated:
+		if r.Version != "" {
+			return fmt.Sprintf("%s: %s to version %s (%d bytes)", r.Name, r.Status, r.Version, r.Written)
+		}
+		return fmt.Sprintf("%s: %s (%d bytes)", r.Name, r.Status, r.Written)
+	case StatusUnchanged:
+		if r.Version != "" {
+			return fmt.Sprintf("%s: %s (version %s)", r.Name, r.Status, r.Version)
+		}
+		return fmt.Sprintf("%s: %s", r.Name, r.Status)
+	default:
+		return fmt.Sprintf("%s: %s", r.Name, r.Status)
+	}
+}
+
+// Results are the results of copying Seeds
+type Results []Result
+
+// Count returns the number of results with the status
+func (rs Results) Count(status Status) int {
+	var n int
+	for _, r := range rs {
+		if r.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed reports whether any seed failed
+func (rs Results) Failed() bool {
+	return rs.Count(StatusFailed) > 0
+}
+
+// Missing reports whether any required seed failed because its source does
+// not exist
+func (rs Results) Missing() bool {
+	for _, r := range rs {
+		if r.Missing() {
+			return true
+		}
+	}
+	return false
+}
+
+// String summarizes the results, e.g. "3 seeds: 1 updated, 1 unchanged, 1 failed"
+func (rs Results) String() string {
+	var counts []string
+	for _, status := range []Status{StatusUpdated, StatusUnchanged, StatusSkipped, StatusFailed} {
+		if n := rs.Count(status); n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, status))
+		}
+	}
+	if len(counts) == 0 {
+		return fmt.Sprintf("%d seeds", len(rs))
+	}
+	return fmt.Sprintf("%d seeds: %s", len(rs), strings.Join(counts, ", "))
+}
@@ -0,0 +1,87 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// DefaultRetryPolicy is the retry policy of seeds, unless configured otherwise
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Jitter:      true,
+}
+
+// RetryPolicy is how often and how long to wait before fetching a source again
+// after a temporary failure. The delay doubles after every attempt, starting at
+// BaseDelay and up to MaxDelay. With Jitter, a random delay between half and
+// all of the delay is used instead.
+type RetryPolicy struct {
+	MaxAttempts int           `mapstructure:"maxAttempts"`
+	BaseDelay   time.Duration `mapstructure:"baseDelay"`
+	MaxDelay    time.Duration `mapstructure:"maxDelay"`
+	Jitter      bool          `mapstructure:"jitter"`
+}
+
+var (
+	randMu sync.Mutex
+	random = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Delay returns how long to wait after the given attempt, starting at 1
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter && delay > 1 {
+		randMu.Lock()
+		delay = delay/2 + time.Duration(random.Int63n(int64(delay/2)))
+		randMu.Unlock()
+	}
+	return delay
+}
+
+// Do calls fn until it succeeds, returns an error that is not retryable, or
+// the maximum number of attempts is reached. It returns the number of
+// attempts and the last error.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) (int, error) {
+	var attempt int
+	for {
+		attempt++
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !errors.Is(err, internal.ErrRetryable) {
+			return attempt, err
+		}
+
+		timer := time.NewTimer(p.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+	}
+}
+
+func (p RetryPolicy) validate(l *errorList, field string) {
+	if p.MaxAttempts < 1 {
+		l.add(joinField(field, "maxAttempts"), errors.New("must be at least 1"))
+	}
+	if p.BaseDelay < 0 {
+		l.add(joinField(field, "baseDelay"), errors.New("must not be negative"))
+	}
+	if p.MaxDelay < p.BaseDelay {
+		l.add(joinField(field, "maxDelay"), errors.New("must not be less than baseDelay"))
+	}
+}
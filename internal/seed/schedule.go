@@ -0,0 +1,83 @@
+package seed
+
+import (
+	"math/rand"
+	"time"
+)
+
+// MinInterval is the shortest interval seeds can be copied at
+const MinInterval = time.Second
+
+// Schedule keeps track of when each seed is next due to be copied. Each seed
+// is copied at its own interval (or the default interval), plus a random
+// jitter so that many instances of seeder do not copy at the same time.
+type Schedule struct {
+	seeds    Seeds
+	interval time.Duration
+	jitter   time.Duration
+	next     []time.Time
+	rand     *rand.Rand
+}
+
+// NewSchedule creates a new Schedule where every seed is next due one
+// interval after start
+func NewSchedule(seeds Seeds, interval, jitter time.Duration, start time.Time) *Schedule {
+	s := &Schedule{
+		seeds:    seeds,
+		interval: interval,
+		jitter:   jitter,
+		next:     make([]time.Time, len(seeds)),
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	s.Reset(start)
+
+	return s
+}
+
+// Reset schedules every seed one interval after t, for example after all
+// seeds were copied
+func (s *Schedule) Reset(t time.Time) {
+	for i := range s.seeds {
+		s.schedule(i, t)
+	}
+}
+
+// Next returns the time the next seed is due. Without any seeds, nothing is
+// due until one default interval from now.
+func (s *Schedule) Next() time.Time {
+	if len(s.next) == 0 {
+		return time.Now().Add(s.interval)
+	}
+
+	var next time.Time
+	for i, t := range s.next {
+		if i == 0 || t.Before(next) {
+			next = t
+		}
+	}
+	return next
+}
+
+// Due returns the seeds that are due at t, and schedules them again one
+// interval after t
+func (s *Schedule) Due(t time.Time) Seeds {
+	var due Seeds
+	for i := range s.seeds {
+		if !s.next[i].After(t) {
+			due = append(due, s.seeds[i])
+			s.schedule(i, t)
+		}
+	}
+	return due
+}
+
+func (s *Schedule) schedule(i int, t time.Time) {
+	interval := s.seeds[i].Interval
+	if interval <= 0 {
+		interval = s.interval
+	}
+	if s.jitter > 0 {
+		interval += time.Duration(s.rand.Int63n(int64(s.jitter)))
+	}
+	s.next[i] = t.Add(interval)
+}
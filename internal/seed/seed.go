@@ -1,21 +1,38 @@
 package seed
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"io"
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/buzzsurfr/seeder/internal"
+	"github.com/buzzsurfr/seeder/internal/sources/aws/acm"
+	"github.com/buzzsurfr/seeder/internal/sources/aws/kms"
+	"github.com/buzzsurfr/seeder/internal/sources/aws/s3"
 	"github.com/buzzsurfr/seeder/internal/sources/aws/secretsmanager"
 	"github.com/buzzsurfr/seeder/internal/sources/aws/ssm"
+	localsource "github.com/buzzsurfr/seeder/internal/sources/local"
 	"github.com/buzzsurfr/seeder/internal/targets/local"
-	"github.com/spf13/viper"
 )
 
 // Seed is the atomic unit of seeder
 type Seed struct {
-	Name   string
-	Source internal.Source
-	Target internal.Target
+	Name     string
+	Source   internal.Source
+	Target   internal.Target
+	Optional bool
+	Interval time.Duration
+	Retry    RetryPolicy
+
+	// DependsOn are the names of seeds that must be copied before this seed
+	DependsOn []string
 }
 
 // NewSeed creates a new Seed
@@ -24,57 +41,373 @@ func NewSeed(name string, source internal.Source, target internal.Target) *Seed
 		Name:   name,
 		Source: source,
 		Target: target,
+		Retry:  DefaultRetryPolicy,
 	}
 }
 
-// Copy copies seed from source to target
-func (s *Seed) Copy() (written int64, err error) {
-	return io.Copy(s.Target, s.Source)
+// Copy fetches the source and copies seed from source to target. Fetching is
+// retried according to the retry policy of the seed. Targets that implement
+// io.ReaderFrom replace their content as a whole, so that cancelling the
+// context leaves the target as it was. Sources with many entries are written
+// to targets that implement internal.TreeWriter.
+func (s *Seed) Copy(ctx context.Context) Result {
+	attempts, err := s.Retry.Do(ctx, func() error {
+		return s.Source.Fetch(ctx)
+	})
+	if err != nil {
+		// A missing source is fine for an optional seed
+		if s.Optional && errors.Is(err, internal.ErrNotFound) {
+			return Result{Name: s.Name, Status: StatusSkipped, Attempts: attempts, Err: err}
+		}
+		return Result{Name: s.Name, Status: StatusFailed, Attempts: attempts, Err: err}
+	}
+
+	var written int64
+	tree, isTree := s.Source.(internal.Tree)
+	tw, isTreeWriter := s.Target.(internal.TreeWriter)
+	rf, isReaderFrom := s.Target.(io.ReaderFrom)
+	switch {
+	case isTree && isTreeWriter:
+		prune := true
+		if p, ok := tree.(internal.Pruner); ok {
+			prune = p.Prune()
+		}
+		written, err = tw.WriteTree(tree.Entries(), prune)
+	case isTree:
+		err = errors.New("target cannot be written with many entries")
+	case isReaderFrom:
+		written, err = rf.ReadFrom(s.Source)
+	default:
+		written, err = io.Copy(s.Target, s.Source)
+	}
+
+	result := Result{
+		Name:     s.Name,
+		Status:   StatusUpdated,
+		Written:  written,
+		Attempts: attempts,
+	}
+	if v, ok := s.Source.(internal.Versioner); ok {
+		result.Version = v.Version()
+	}
+	switch {
+	case err != nil:
+		result.Status = StatusFailed
+		result.Err = err
+	case !changed(s.Target):
+		result.Status = StatusUnchanged
+	}
+	return result
+}
+
+// changed reports whether the last write changed the target. Targets that
+// cannot tell are always changed.
+func changed(t internal.Target) bool {
+	if c, ok := t.(internal.Changer); ok {
+		return c.Changed()
+	}
+	return true
 }
 
 // Close closes all dependencies of the Seed
 func (s *Seed) Close() error {
-	s.Source.Close()
-	s.Target.Close()
+	sourceErr := s.Source.Close()
+	targetErr := s.Target.Close()
 
+	switch {
+	case sourceErr != nil:
+		return fmt.Errorf("%s: unable to close source: %w", s.Name, sourceErr)
+	case targetErr != nil:
+		return fmt.Errorf("%s: unable to close target: %w", s.Name, targetErr)
+	}
 	return nil
 }
 
 // Seeds are a collection of Seed
 type Seeds []Seed
 
-// UnmarshalSeeds reads a key from viper and returns Seeds
-func UnmarshalSeeds(sess *session.Session, key string) Seeds {
-	items := viper.Get(key)
-	var seeds Seeds
+// Copy copies every seed, at most concurrency at a time, and returns the
+// result of each seed in order. A seed is only copied after the seeds it
+// depends on have been copied, if they are being copied as well. If one of
+// them failed, the seed fails without being copied.
+func (seeds Seeds) Copy(ctx context.Context, concurrency int) Results {
+	if concurrency < 1 {
+		concurrency = 1
+	}
 
-	for _, item := range items.([]interface{}) {
-		seed := item.(map[interface{}]interface{})
-		name := seed["name"].(string)
-		var source internal.Source
-		var target internal.Target
+	results := make(Results, len(seeds))
+	done := make([]chan struct{}, len(seeds))
+	index := make(map[string]int, len(seeds))
+	for i := range seeds {
+		done[i] = make(chan struct{})
+		index[seeds[i].Name] = i
+	}
+
+	seeds.prefetch(ctx)
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range seeds {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+			s := &seeds[i]
+
+			// Wait for dependencies, without taking a place in the pool
+			for _, dep := range s.DependsOn {
+				j, ok := index[dep]
+				if !ok || j == i {
+					continue
+				}
+				<-done[j]
+				if results[j].Status == StatusFailed {
+					results[i] = Result{Name: s.Name, Status: StatusFailed, Err: fmt.Errorf("dependency %s failed", dep)}
+					return
+				}
+			}
 
-		// Source
-		sourceConfig := seed["source"].(map[interface{}]interface{})
-		switch sourceConfig["type"] {
-		case "ssm-parameter":
-			spec := sourceConfig["spec"].(map[interface{}]interface{})
-			source = ssm.NewParameter(sess, spec["name"].(string))
-		case "secretsmanager":
-			spec := sourceConfig["spec"].(map[interface{}]interface{})
-			source = secretsmanager.NewSecret(sess, spec["secretId"].(string))
+			sem <- struct{}{}
+			results[i] = s.Copy(ctx)
+			<-sem
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// prefetch fetches all SSM parameters together, instead of one request per seed
+func (seeds Seeds) prefetch(ctx context.Context) {
+	var params []*ssm.Parameter
+	for i := range seeds {
+		if param, ok := seeds[i].Source.(*ssm.Parameter); ok {
+			params = append(params, param)
+		}
+	}
+	if len(params) > 0 {
+		ssm.FetchParameters(ctx, params)
+	}
+}
+
+// Close closes every seed, and returns the errors of the seeds that could not
+// be closed
+func (seeds Seeds) Close() error {
+	var errs Errors
+	for i := range seeds {
+		if err := seeds[i].Close(); err != nil {
+			errs = append(errs, err)
 		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// UnmarshalSeeds reads a key from viper and returns Seeds. If the
+// configuration is invalid, every problem found is returned as Errors.
+func UnmarshalSeeds(sess *session.Session, key string) (Seeds, error) {
+	cfgs, err := LoadConfig(key)
+	if err != nil {
+		return nil, err
+	}
 
-		// Target
-		targetConfig := seed["target"].(map[interface{}]interface{})
-		switch targetConfig["type"] {
-		case "file":
-			spec := targetConfig["spec"].(map[interface{}]interface{})
-			target = local.NewFile(spec["path"].(string), spec["name"].(string))
+	sessions := newSessions(sess)
+	var seeds Seeds
+	var errs Errors
+	for i, cfg := range cfgs {
+		l := errorList{seed: cfg.Name, path: fmt.Sprintf("%s[%d]", key, i)}
+
+		source, err := newSource(sessions, cfg.Source, cfgs)
+		if err != nil {
+			l.add("source.spec", err)
+		}
+		tl := l.sub("target")
+		target := newTarget(cfg.Target, tl)
+		l.errs = append(l.errs, tl.errs...)
+		if len(l.errs) > 0 {
+			errs = append(errs, l.errs...)
+			continue
 		}
 
 		// Add seed to seeds
-		seeds = append(seeds, *NewSeed(name, source, target))
+		seed := NewSeed(cfg.Name, source, target)
+		seed.Optional = cfg.Optional
+		seed.Interval = cfg.Interval
+		seed.Retry = cfg.retry
+		seed.DependsOn = cfg.DependsOn
+		seeds = append(seeds, *seed)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return seeds, nil
+}
+
+// newTarget creates the target of a seed, adding any problem to l
+func newTarget(cfg TargetConfig, l *errorList) internal.Target {
+	switch spec := cfg.spec.(type) {
+	case *FileSpec:
+		sl := l.sub("spec")
+		opts := spec.fileOpts(sl)
+		l.errs = append(l.errs, sl.errs...)
+		return local.NewFile(spec.Path, spec.Name, opts...)
+	}
+	l.addf("type", "unsupported target type %q", cfg.Type)
+	return nil
+}
+
+// newSource creates the source of a seed. Seeds never share sources, so a
+// source that needs the value of another seed gets a new source of its own.
+func newSource(sessions *sessions, cfg SourceConfig, cfgs []Config) (internal.Source, error) {
+	sess, err := sessions.get(cfg.spec)
+	if err != nil {
+		return nil, err
 	}
-	return seeds
+
+	switch spec := cfg.spec.(type) {
+	case *SSMParameterSpec:
+		var opts []ssm.ParameterOpt
+		switch {
+		case spec.Version != 0:
+			opts = append(opts, ssm.WithParameterVersion(spec.Version))
+		case spec.Label != "":
+			opts = append(opts, ssm.WithParameterLabel(spec.Label))
+		}
+		return ssm.NewParameter(sess, spec.Name, opts...), nil
+	case *SSMPathSpec:
+		return ssm.NewPath(sess, spec.Path), nil
+	case *SecretsManagerSpec:
+		var opts []secretsmanager.SecretOpt
+		if spec.VersionStage != "" {
+			opts = append(opts, secretsmanager.WithSecretVersionStage(spec.VersionStage))
+		}
+		if spec.VersionID != "" {
+			opts = append(opts, secretsmanager.WithSecretVersionID(spec.VersionID))
+		}
+		if spec.Encoding != "" {
+			opts = append(opts, secretsmanager.WithSecretEncoding(spec.Encoding))
+		}
+		if spec.Key != "" {
+			opts = append(opts, secretsmanager.WithSecretKey(spec.Key))
+		}
+		if spec.Explode {
+			return secretsmanager.NewExploded(sess, spec.SecretID, opts...), nil
+		}
+		return secretsmanager.NewSecret(sess, spec.SecretID, opts...), nil
+	case *S3ObjectSpec:
+		var opts []s3.ObjectOpt
+		if spec.ForcePathStyle {
+			opts = append(opts, s3.WithObjectForcePathStyle(true))
+		}
+		if spec.URI != "" {
+			// Validated already
+			u, _ := spec.parseURI()
+			return s3.NewObjectFromURI(sess, u, opts...), nil
+		}
+		if spec.VersionID != "" {
+			opts = append(opts, s3.WithObjectVersionID(spec.VersionID))
+		}
+		return s3.NewObject(sess, spec.Bucket, spec.Key, opts...), nil
+	case *S3PrefixSpec:
+		var opts []s3.PrefixOpt
+		if spec.ForcePathStyle {
+			opts = append(opts, s3.WithPrefixForcePathStyle(true))
+		}
+		if len(spec.Include) > 0 {
+			opts = append(opts, s3.WithPrefixInclude(spec.Include...))
+		}
+		if len(spec.Exclude) > 0 {
+			opts = append(opts, s3.WithPrefixExclude(spec.Exclude...))
+		}
+		if spec.Delete {
+			opts = append(opts, s3.WithPrefixDelete(true))
+		}
+		if spec.URI != "" {
+			// Validated already
+			u, _ := spec.parseURI()
+			return s3.NewPrefixFromURI(sess, u, opts...), nil
+		}
+		return s3.NewPrefix(sess, spec.Bucket, spec.Prefix, opts...), nil
+	case *ACMCertificateSpec:
+		var opts []acm.CertificateOpt
+		if spec.PassphraseFrom != "" {
+			for _, other := range cfgs {
+				if other.Name == spec.PassphraseFrom {
+					passphrase, err := newSource(sessions, other.Source, cfgs)
+					if err != nil {
+						return nil, err
+					}
+					opts = append(opts, acm.WithPassphrase(passphrase))
+					break
+				}
+			}
+		}
+		return acm.NewCertificate(sess, spec.CertificateARN, opts...), nil
+	case *KMSDecryptSpec:
+		var source internal.Source
+		if spec.Source != nil {
+			if source, err = newSource(sessions, *spec.Source, cfgs); err != nil {
+				return nil, err
+			}
+		} else {
+			// Validated already
+			ciphertext, _ := base64.StdEncoding.DecodeString(spec.Ciphertext)
+			source = localsource.NewValue(ciphertext)
+		}
+		var opts []kms.CiphertextOpt
+		if len(spec.EncryptionContext) > 0 {
+			opts = append(opts, kms.WithEncryptionContext(spec.EncryptionContext))
+		}
+		if spec.KeyID != "" {
+			opts = append(opts, kms.WithKeyID(spec.KeyID))
+		}
+		if spec.Encoding != "" {
+			opts = append(opts, kms.WithEncoding(spec.Encoding))
+		}
+		return kms.NewCiphertext(sess, source, opts...), nil
+	case *FileSourceSpec:
+		return localsource.NewFile(spec.Path), nil
+	}
+	return nil, fmt.Errorf("unsupported source type %q", cfg.Type)
+}
+
+// fileOpts converts the spec to options of a File, looking up owner and group
+// names on the local system. Names that cannot be looked up are added to l.
+func (spec *FileSpec) fileOpts(l *errorList) []local.FileOpt {
+	var opts []local.FileOpt
+	if spec.Mode != 0 {
+		opts = append(opts, local.WithMode(spec.Mode))
+	}
+	if spec.DirMode != 0 {
+		opts = append(opts, local.WithDirMode(spec.DirMode))
+	}
+
+	uid, gid := -1, -1
+	if spec.UID != nil {
+		uid = *spec.UID
+	}
+	if spec.GID != nil {
+		gid = *spec.GID
+	}
+	if spec.Owner != "" {
+		if u, err := user.Lookup(spec.Owner); err != nil {
+			l.add("owner", err)
+		} else if uid, err = strconv.Atoi(u.Uid); err != nil {
+			l.add("owner", err)
+		}
+	}
+	if spec.Group != "" {
+		if g, err := user.LookupGroup(spec.Group); err != nil {
+			l.add("group", err)
+		} else if gid, err = strconv.Atoi(g.Gid); err != nil {
+			l.add("group", err)
+		}
+	}
+	if uid != -1 || gid != -1 {
+		opts = append(opts, local.WithOwner(uid, gid))
+	}
+
+	return opts
 }
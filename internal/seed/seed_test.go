@@ -0,0 +1,157 @@
+package seed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// stubSource is a source with a fixed value or error, which records when it
+// is fetched
+type stubSource struct {
+	name  string
+	value string
+	err   error
+	delay time.Duration
+	log   *fetchLog
+	r     io.Reader
+}
+
+func (s *stubSource) Fetch(ctx context.Context) error {
+	s.log.start(s.name)
+	defer s.log.end()
+	time.Sleep(s.delay)
+	if s.err != nil {
+		return s.err
+	}
+	s.r = strings.NewReader(s.value)
+	return nil
+}
+
+func (s *stubSource) Read(b []byte) (int, error) {
+	return s.r.Read(b)
+}
+
+func (s *stubSource) Close() error {
+	return nil
+}
+
+// stubTarget is a target in memory
+type stubTarget struct {
+	bytes.Buffer
+}
+
+func (t *stubTarget) Close() error {
+	return nil
+}
+
+// fetchLog records the order of fetches, and how many run at the same time
+type fetchLog struct {
+	mu      sync.Mutex
+	order   []string
+	running int
+	max     int
+}
+
+func (l *fetchLog) start(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.order = append(l.order, name)
+	l.running++
+	if l.running > l.max {
+		l.max = l.running
+	}
+}
+
+func (l *fetchLog) end() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.running--
+}
+
+func (l *fetchLog) index(name string) int {
+	for i, n := range l.order {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func newStubSeed(log *fetchLog, name string, source *stubSource, dependsOn ...string) Seed {
+	source.name = name
+	source.log = log
+	s := NewSeed(name, source, &stubTarget{})
+	s.Retry.MaxAttempts = 1
+	s.DependsOn = dependsOn
+	return *s
+}
+
+func TestSeedsCopy(t *testing.T) {
+	log := &fetchLog{}
+	seeds := Seeds{
+		newStubSeed(log, "app", &stubSource{value: "app"}, "config", "certs"),
+		newStubSeed(log, "config", &stubSource{value: "config", delay: 20 * time.Millisecond}),
+		newStubSeed(log, "certs", &stubSource{value: "certs", delay: 10 * time.Millisecond}),
+		newStubSeed(log, "optional", &stubSource{err: internal.NotFound(errors.New("gone"))}),
+		newStubSeed(log, "required", &stubSource{err: internal.NotFound(errors.New("gone"))}),
+		newStubSeed(log, "broken", &stubSource{err: errors.New("access denied")}),
+		newStubSeed(log, "dependent", &stubSource{value: "dependent"}, "broken"),
+		newStubSeed(log, "after-optional", &stubSource{value: "after"}, "optional"),
+	}
+	seeds[3].Optional = true
+
+	results := seeds.Copy(context.Background(), 2)
+
+	want := []struct {
+		name   string
+		status Status
+	}{
+		{"app", StatusUpdated},
+		{"config", StatusUpdated},
+		{"certs", StatusUpdated},
+		{"optional", StatusSkipped},
+		{"required", StatusFailed},
+		{"broken", StatusFailed},
+		{"dependent", StatusFailed},
+		{"after-optional", StatusUpdated},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Copy() = %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		if results[i].Name != w.name || results[i].Status != w.status {
+			t.Errorf("result %d = %v, want %s %s", i, results[i], w.name, w.status)
+		}
+	}
+
+	if got := seeds[0].Target.(*stubTarget).String(); got != "app" {
+		t.Errorf("target of app = %q, want %q", got, "app")
+	}
+	if !results[4].Missing() {
+		t.Errorf("required seed is not missing: %v", results[4])
+	}
+	if msg := fmt.Sprint(results[6].Err); msg != "dependency broken failed" {
+		t.Errorf("dependent error = %q, want the failed dependency", msg)
+	}
+
+	// Dependencies are copied first, and seeds of failed dependencies not at all
+	app := log.index("app")
+	if app < log.index("config") || app < log.index("certs") {
+		t.Errorf("fetch order = %v, want app after config and certs", log.order)
+	}
+	if i := log.index("dependent"); i != -1 {
+		t.Errorf("fetch order = %v, want dependent not fetched", log.order)
+	}
+	if log.max > 2 {
+		t.Errorf("%d fetches at the same time, want at most 2", log.max)
+	}
+}
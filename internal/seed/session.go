@@ -0,0 +1,142 @@
+package seed
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/buzzsurfr/seeder/internal/sources/aws/s3"
+	"github.com/spf13/viper"
+)
+
+// DefaultSessionName is the name of sessions of assumed roles, unless
+// configured otherwise
+const DefaultSessionName = "seeder"
+
+// AWSSpec is the part of the spec of AWS sources that selects the region,
+// credentials and endpoint to use instead of the default session
+type AWSSpec struct {
+	Endpoint    string `mapstructure:"endpoint"`
+	Region      string `mapstructure:"region"`
+	Profile     string `mapstructure:"profile"`
+	RoleARN     string `mapstructure:"roleArn"`
+	ExternalID  string `mapstructure:"externalId"`
+	SessionName string `mapstructure:"sessionName"`
+}
+
+func (spec AWSSpec) awsSpec() AWSSpec {
+	return spec
+}
+
+// endpoint returns the endpoint of the source, or the "default.endpoint" key
+func (spec AWSSpec) endpoint() string {
+	if spec.Endpoint != "" {
+		return spec.Endpoint
+	}
+	return viper.GetString("default.endpoint")
+}
+
+// awsSpecer is implemented by the specs of AWS sources, through AWSSpec
+type awsSpecer interface {
+	awsSpec() AWSSpec
+}
+
+func (spec AWSSpec) validate(l *errorList, field string) {
+	if spec.Endpoint != "" && s3.EndpointHost(spec.Endpoint) == "" {
+		l.addf(joinField(field, "endpoint"), "invalid endpoint %q", spec.Endpoint)
+	}
+	if spec.RoleARN == "" {
+		if spec.ExternalID != "" {
+			l.add(joinField(field, "externalId"), errors.New("requires roleArn"))
+		}
+		if spec.SessionName != "" {
+			l.add(joinField(field, "sessionName"), errors.New("requires roleArn"))
+		}
+	}
+}
+
+// sessions creates the sessions of AWS sources. Sources with the same region
+// and credentials share a session, so that credentials of assumed roles are
+// cached and refreshed once.
+type sessions struct {
+	base     *session.Session
+	defaults *aws.Config
+	cache    map[AWSSpec]*session.Session
+
+	// Session without the default endpoint, to assume roles with STS
+	sts *session.Session
+}
+
+// newSessions creates the sessions of AWS sources, applying the
+// "default.endpoint" and "default.forcePathStyle" keys to the base session
+// and to sessions of profiles
+func newSessions(base *session.Session) *sessions {
+	defaults := aws.NewConfig()
+	if endpoint := viper.GetString("default.endpoint"); endpoint != "" {
+		defaults = defaults.WithEndpoint(endpoint)
+	}
+	if viper.GetBool("default.forcePathStyle") {
+		defaults = defaults.WithS3ForcePathStyle(true)
+	}
+
+	return &sessions{
+		base:     base.Copy(defaults),
+		defaults: defaults,
+		cache:    make(map[AWSSpec]*session.Session),
+		sts:      base,
+	}
+}
+
+// get returns the session for the spec of a source. Roles are assumed with
+// STS itself, not the endpoint of the source.
+func (s *sessions) get(spec interface{}) (*session.Session, error) {
+	specer, ok := spec.(awsSpecer)
+	if !ok {
+		return s.base, nil
+	}
+	key := specer.awsSpec()
+	if key == (AWSSpec{}) {
+		return s.base, nil
+	}
+	if sess, ok := s.cache[key]; ok {
+		return sess, nil
+	}
+
+	sess, stsSess := s.base, s.sts
+	if key.Profile != "" {
+		var err error
+		stsSess, err = session.NewSessionWithOptions(session.Options{
+			Profile:           key.Profile,
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sess = stsSess.Copy(s.defaults)
+	}
+
+	cfg := aws.NewConfig()
+	if key.Endpoint != "" {
+		cfg = cfg.WithEndpoint(key.Endpoint)
+	}
+	if key.Region != "" {
+		cfg = cfg.WithRegion(key.Region)
+	}
+	if key.RoleARN != "" {
+		sessionName := key.SessionName
+		if sessionName == "" {
+			sessionName = DefaultSessionName
+		}
+		cfg = cfg.WithCredentials(stscreds.NewCredentials(stsSess, key.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			p.RoleSessionName = sessionName
+			if key.ExternalID != "" {
+				p.ExternalID = aws.String(key.ExternalID)
+			}
+		}))
+	}
+	sess = sess.Copy(cfg)
+
+	s.cache[key] = sess
+	return sess, nil
+}
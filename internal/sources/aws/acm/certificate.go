@@ -0,0 +1,182 @@
+package acm
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awsAcm "github.com/aws/aws-sdk-go/service/acm"
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// Names of the entries of a Certificate
+const (
+	CertificateName = "cert.pem"
+	ChainName       = "chain.pem"
+	FullChainName   = "fullchain.pem"
+	PrivateKeyName  = "key.pem"
+)
+
+// Certificate represents a seed that sources from an exportable AWS Certificate
+// Manager certificate, such as a certificate issued by ACM Private CA. The
+// certificate, chain, certificate with chain, and decrypted private key are
+// separate entries.
+//
+// The certificate is only exported again when its serial number changes, for
+// example when ACM renewed it.
+type Certificate struct {
+	ARN        string
+	Passphrase internal.Source
+	sess       *session.Session
+	serial     string
+	entries    []internal.Entry
+}
+
+// CertificateOpt is the functional options set for a Certificate
+type CertificateOpt func(*Certificate)
+
+// WithPassphrase is a functional option to export the certificate with the
+// passphrase from a source. Without it, a random passphrase is used.
+func WithPassphrase(source internal.Source) CertificateOpt {
+	return func(c *Certificate) {
+		c.Passphrase = source
+	}
+}
+
+// NewCertificate creates a new Certificate seed
+func NewCertificate(sess *session.Session, arn string, opts ...CertificateOpt) *Certificate {
+	c := Certificate{
+		ARN:  arn,
+		sess: sess,
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+
+	return &c
+}
+
+// Fetch exports the certificate if it was renewed since the last Fetch, and
+// decrypts its private key
+func (c *Certificate) Fetch(ctx context.Context) error {
+	acmSvc := awsAcm.New(c.sess)
+
+	desc, err := acmSvc.DescribeCertificateWithContext(ctx, &awsAcm.DescribeCertificateInput{
+		CertificateArn: aws.String(c.ARN),
+	})
+	if err != nil {
+		return c.fetchError(err)
+	}
+	serial := aws.StringValue(desc.Certificate.Serial)
+	if serial != "" && serial == c.serial {
+		return nil
+	}
+
+	passphrase, err := c.passphrase(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := acmSvc.ExportCertificateWithContext(ctx, &awsAcm.ExportCertificateInput{
+		CertificateArn: aws.String(c.ARN),
+		Passphrase:     passphrase,
+	})
+	if err != nil {
+		return c.fetchError(err)
+	}
+
+	key, err := decryptPrivateKey([]byte(aws.StringValue(result.PrivateKey)), passphrase)
+	if err != nil {
+		return fmt.Errorf("unable to decrypt private key of certificate %s: %w", c.ARN, err)
+	}
+	cert := []byte(aws.StringValue(result.Certificate))
+	chain := []byte(aws.StringValue(result.CertificateChain))
+	fullChain := append([]byte{}, cert...)
+	if len(fullChain) > 0 && fullChain[len(fullChain)-1] != '\n' {
+		fullChain = append(fullChain, '\n')
+	}
+	fullChain = append(fullChain, chain...)
+
+	c.entries = []internal.Entry{
+		{Name: CertificateName, Value: cert},
+		{Name: ChainName, Value: chain},
+		{Name: FullChainName, Value: fullChain},
+		{Name: PrivateKeyName, Value: key, Secret: true},
+	}
+	c.serial = serial
+	return nil
+}
+
+// passphrase returns the passphrase from the passphrase source, or else a new
+// random passphrase
+func (c *Certificate) passphrase(ctx context.Context) ([]byte, error) {
+	if c.Passphrase == nil {
+		b := make([]byte, 24)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		// Passphrases must not contain #, $ or %
+		return []byte(base64.RawURLEncoding.EncodeToString(b)), nil
+	}
+
+	if err := c.Passphrase.Fetch(ctx); err != nil {
+		return nil, fmt.Errorf("unable to get passphrase: %w", err)
+	}
+	b, err := ioutil.ReadAll(c.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get passphrase: %w", err)
+	}
+	b = bytes.TrimRight(b, "\r\n")
+	if len(b) == 0 {
+		return nil, errors.New("passphrase is empty")
+	}
+	return b, nil
+}
+
+// Entries returns the certificate, chain and private key of the last Fetch
+func (c *Certificate) Entries() []internal.Entry {
+	return c.entries
+}
+
+// Read fails, as the certificate can only be written as entries
+func (c *Certificate) Read(b []byte) (int, error) {
+	return 0, fmt.Errorf("certificate %s has many parts, which must be written to a directory", c.ARN)
+}
+
+// Version returns the serial number of the certificate that was last exported
+func (c *Certificate) Version() string {
+	return c.serial
+}
+
+// IsSecret reports true, as the private key is secret
+func (c *Certificate) IsSecret() bool {
+	return true
+}
+
+// Close closes the passphrase source
+func (c *Certificate) Close() error {
+	if c.Passphrase == nil {
+		return nil
+	}
+	return c.Passphrase.Close()
+}
+
+func (c *Certificate) fetchError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case awsAcm.ErrCodeResourceNotFoundException:
+			return internal.NotFound(fmt.Errorf("certificate %s not found: %w", c.ARN, err))
+		case awsAcm.ErrCodeInvalidArnException:
+			return fmt.Errorf("invalid certificate ARN %s: %w", c.ARN, err)
+		case awsAcm.ErrCodeRequestInProgressException:
+			return internal.Retryable(fmt.Errorf("certificate %s is not ready: %w", c.ARN, err))
+		}
+	}
+	return internal.ClassifyAWS(err, fmt.Errorf("unable to export certificate %s: %w", c.ARN, err))
+}
@@ -0,0 +1,163 @@
+package acm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Object identifiers of PBES2 (RFC 8018), as used by ExportCertificate
+var (
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// ErrIncorrectPassphrase is returned when a private key cannot be decrypted
+// with the passphrase
+var ErrIncorrectPassphrase = errors.New("incorrect passphrase for private key")
+
+// decryptPrivateKey decrypts a PEM encoded private key, as returned by
+// ExportCertificate, and returns it as an unencrypted PKCS #8 PEM block
+func decryptPrivateKey(data, passphrase []byte) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+
+	if block.Type != "ENCRYPTED PRIVATE KEY" {
+		return nil, fmt.Errorf("unexpected private key type %q", block.Type)
+	}
+	der, err := decryptPKCS8(block.Bytes, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// decryptPKCS8 decrypts a PKCS #8 EncryptedPrivateKeyInfo encrypted with PBES2
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("invalid encrypted private key: %w", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported private key encryption %s", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("invalid PBES2 parameters: %w", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function %s", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("invalid PBKDF2 parameters: %w", err)
+	}
+
+	var prf func() hash.Hash
+	switch alg := kdf.PRF.Algorithm; {
+	case len(alg) == 0, alg.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case alg.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case alg.Equal(oidHMACWithSHA384):
+		prf = sha512.New384
+	case alg.Equal(oidHMACWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 function %s", alg)
+	}
+
+	var keyLen int
+	var newCipher func([]byte) (cipher.Block, error)
+	switch alg := params.EncryptionScheme.Algorithm; {
+	case alg.Equal(oidAES128CBC):
+		keyLen, newCipher = 16, aes.NewCipher
+	case alg.Equal(oidAES192CBC):
+		keyLen, newCipher = 24, aes.NewCipher
+	case alg.Equal(oidAES256CBC):
+		keyLen, newCipher = 32, aes.NewCipher
+	case alg.Equal(oidDESEDE3CBC):
+		keyLen, newCipher = 24, des.NewTripleDESCipher
+	default:
+		return nil, fmt.Errorf("unsupported private key cipher %s", alg)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("invalid cipher parameters: %w", err)
+	}
+
+	key := pbkdf2.Key(passphrase, kdf.Salt, kdf.IterationCount, keyLen, prf)
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() || len(info.EncryptedData)%block.BlockSize() != 0 {
+		return nil, errors.New("invalid encrypted private key length")
+	}
+	data := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, info.EncryptedData)
+
+	// A wrong passphrase almost always results in invalid padding, and
+	// otherwise in an invalid key
+	n := len(data)
+	if n == 0 {
+		return nil, ErrIncorrectPassphrase
+	}
+	pad := int(data[n-1])
+	if pad == 0 || pad > block.BlockSize() || pad > n {
+		return nil, ErrIncorrectPassphrase
+	}
+	for _, b := range data[n-pad:] {
+		if int(b) != pad {
+			return nil, ErrIncorrectPassphrase
+		}
+	}
+	data = data[:n-pad]
+	if _, err := x509.ParsePKCS8PrivateKey(data); err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+	return data, nil
+}
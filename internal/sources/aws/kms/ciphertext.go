@@ -0,0 +1,153 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awsKms "github.com/aws/aws-sdk-go/service/kms"
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// EncodingBase64 is the Encoding of ciphertext stored base64-encoded, as
+// printed by the AWS CLI
+const EncodingBase64 = "base64"
+
+// Ciphertext represents a seed that decrypts KMS ciphertext from another
+// source. Only the plaintext is returned by Read.
+type Ciphertext struct {
+	Source            internal.Source
+	EncryptionContext map[string]string
+	KeyID             string
+	Encoding          string
+	sess              *session.Session
+	value             []byte
+	r                 io.Reader
+}
+
+// CiphertextOpt is the functional options set for a Ciphertext
+type CiphertextOpt func(*Ciphertext)
+
+// WithEncryptionContext is a functional option to decrypt with the encryption
+// context the ciphertext was encrypted with
+func WithEncryptionContext(ctx map[string]string) CiphertextOpt {
+	return func(c *Ciphertext) {
+		c.EncryptionContext = ctx
+	}
+}
+
+// WithKeyID is a functional option to only decrypt ciphertext of a KMS key
+func WithKeyID(keyID string) CiphertextOpt {
+	return func(c *Ciphertext) {
+		c.KeyID = keyID
+	}
+}
+
+// WithEncoding is a functional option to decode the ciphertext first, such as
+// EncodingBase64
+func WithEncoding(encoding string) CiphertextOpt {
+	return func(c *Ciphertext) {
+		c.Encoding = encoding
+	}
+}
+
+// NewCiphertext creates a new Ciphertext seed
+func NewCiphertext(sess *session.Session, source internal.Source, opts ...CiphertextOpt) *Ciphertext {
+	c := Ciphertext{
+		Source: source,
+		sess:   sess,
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+
+	return &c
+}
+
+// Fetch gets the latest ciphertext from the source and decrypts it
+func (c *Ciphertext) Fetch(ctx context.Context) error {
+	if err := c.Source.Fetch(ctx); err != nil {
+		return err
+	}
+	blob, err := ioutil.ReadAll(c.Source)
+	if err != nil {
+		return fmt.Errorf("unable to read ciphertext: %w", err)
+	}
+	switch c.Encoding {
+	case "":
+	case EncodingBase64:
+		if blob, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(blob))); err != nil {
+			return fmt.Errorf("unable to decode ciphertext: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown encoding %q for ciphertext", c.Encoding)
+	}
+
+	input := &awsKms.DecryptInput{
+		CiphertextBlob: blob,
+	}
+	if len(c.EncryptionContext) > 0 {
+		input.EncryptionContext = aws.StringMap(c.EncryptionContext)
+	}
+	if c.KeyID != "" {
+		input.KeyId = aws.String(c.KeyID)
+	}
+	kmsSvc := awsKms.New(c.sess)
+	result, err := kmsSvc.DecryptWithContext(ctx, input)
+	if err != nil {
+		return c.fetchError(err)
+	}
+
+	c.value = result.Plaintext
+	c.r = bytes.NewReader(c.value)
+	return nil
+}
+
+func (c *Ciphertext) Read(b []byte) (int, error) {
+	if c.r == nil {
+		if err := c.Fetch(context.Background()); err != nil {
+			return 0, err
+		}
+	}
+
+	// Trap io.EOF and reset reader (so that the reader is always ready)
+	n, err := c.r.Read(b)
+	if err == io.EOF {
+		c.r = bytes.NewReader(c.value)
+	}
+	return n, err
+}
+
+// IsSecret reports true, as the plaintext of ciphertext is always secret
+func (c *Ciphertext) IsSecret() bool {
+	return true
+}
+
+// Close closes the source of the ciphertext
+func (c *Ciphertext) Close() error {
+	return c.Source.Close()
+}
+
+func (c *Ciphertext) fetchError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case awsKms.ErrCodeNotFoundException:
+			return internal.NotFound(fmt.Errorf("key to decrypt ciphertext not found: %w", err))
+		case awsKms.ErrCodeInvalidCiphertextException:
+			return fmt.Errorf("invalid ciphertext or encryption context: %w", err)
+		case awsKms.ErrCodeIncorrectKeyException:
+			return fmt.Errorf("ciphertext was not encrypted with key %s: %w", c.KeyID, err)
+		case awsKms.ErrCodeDisabledException:
+			return fmt.Errorf("key to decrypt ciphertext is disabled: %w", err)
+		case awsKms.ErrCodeInternalException, awsKms.ErrCodeDependencyTimeoutException, awsKms.ErrCodeKeyUnavailableException:
+			return internal.Retryable(fmt.Errorf("kms failed to decrypt ciphertext: %w", err))
+		}
+	}
+	return internal.ClassifyAWS(err, fmt.Errorf("unable to decrypt ciphertext: %w", err))
+}
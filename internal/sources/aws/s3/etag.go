@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awsS3 "github.com/aws/aws-sdk-go/service/s3"
+)
+
+// isNotModified reports whether a conditional GetObject failed because the
+// object did not change
+func isNotModified(err error) bool {
+	if rerr, ok := err.(awserr.RequestFailure); ok && rerr.StatusCode() == http.StatusNotModified {
+		return true
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == errCodeNotModified {
+		return true
+	}
+	return false
+}
+
+// verifyETag checks the content of an object against its ETag, when the ETag
+// is the MD5 digest of the content. This is not the case for objects uploaded
+// in parts, or encrypted with SSE-KMS or SSE-C, which are not checked.
+func verifyETag(value []byte, result *awsS3.GetObjectOutput) error {
+	etag := strings.Trim(aws.StringValue(result.ETag), `"`)
+	if len(etag) != 2*md5.Size || strings.Contains(etag, "-") {
+		return nil
+	}
+	if aws.StringValue(result.ServerSideEncryption) == awsS3.ServerSideEncryptionAwsKms ||
+		aws.StringValue(result.SSECustomerAlgorithm) != "" {
+		return nil
+	}
+	// Partial content cannot be checked against the digest of the object
+	if result.ContentRange != nil {
+		return nil
+	}
+
+	sum := md5.Sum(value)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), etag) {
+		return fmt.Errorf("content does not match ETag %s", etag)
+	}
+	return nil
+}
@@ -1,94 +1,246 @@
 package s3
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	awsS3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// Error codes of S3 that the SDK has no constants for
+const (
+	errCodeNotFound           = "NotFound"
+	errCodeNotModified        = "NotModified"
+	errCodeSlowDown           = "SlowDown"
+	errCodeInternalError      = "InternalError"
+	errCodeServiceUnavailable = "ServiceUnavailable"
 )
 
 // Object is a S3 object seed
 type Object struct {
-	Bucket      string
-	Key         string
-	Value       string
-	sess        *session.Session
-	r           io.ReadCloser
-	isRead      bool
-	lastUpdated time.Time
+	Bucket         string
+	Key            string
+	VersionID      string
+	Region         string
+	ForcePathStyle bool
+	Value          string
+	sess           *session.Session
+	lookupRegion   bool
+	value          []byte
+	r              io.Reader
+	etag           string
+	lastModified   time.Time
+}
+
+// ObjectOpt is the functional options set for an Object
+type ObjectOpt func(*Object)
+
+// WithObjectVersionID is a functional option to read a specific version of the object
+func WithObjectVersionID(s string) ObjectOpt {
+	return func(obj *Object) {
+		obj.VersionID = s
+	}
+}
+
+// WithObjectRegion is a functional option to specify the region of the bucket
+func WithObjectRegion(s string) ObjectOpt {
+	return func(obj *Object) {
+		obj.Region = s
+	}
+}
+
+// WithObjectForcePathStyle is a functional option to address the bucket in the
+// path of requests instead of the host, as needed by some custom endpoints
+func WithObjectForcePathStyle(b bool) ObjectOpt {
+	return func(obj *Object) {
+		obj.ForcePathStyle = b
+	}
 }
 
-// NewFromURI creates a new object from a S3 URI
-func NewFromURI(sess *session.Session, location string) *Object {
-	u, parseErr := ParseString(location)
-	if parseErr != nil {
-		return &Object{}
+// NewObjectFromURI creates a new object from a parsed URI, honoring the
+// version ID and region of the URI. Options override the URI. The region of
+// s3:// URIs is looked up by the first Fetch.
+func NewObjectFromURI(sess *session.Session, u *URI, opts ...ObjectOpt) *Object {
+	var uriOpts []ObjectOpt
+	if u.VersionID != nil {
+		uriOpts = append(uriOpts, WithObjectVersionID(StringValue(u.VersionID)))
 	}
-	return NewObject(sess, *u.Bucket, *u.Key)
+	region, lookup := uriRegion(sess, u)
+	if region != "" {
+		uriOpts = append(uriOpts, WithObjectRegion(region))
+	}
+	if uriForcePathStyle(sess, u) {
+		uriOpts = append(uriOpts, WithObjectForcePathStyle(true))
+	}
+
+	obj := NewObject(sess, StringValue(u.Bucket), StringValue(u.Key), append(uriOpts, opts...)...)
+	obj.lookupRegion = lookup && obj.Region == ""
+	return obj
 }
 
 // NewObject creates a new object from a bucket and key
-func NewObject(sess *session.Session, bucket, key string) *Object {
+func NewObject(sess *session.Session, bucket, key string, opts ...ObjectOpt) *Object {
 	obj := Object{
 		Bucket: bucket,
 		Key:    key,
+		sess:   sess,
+	}
+	for _, o := range opts {
+		o(&obj)
 	}
 
-	obj.fetch()
+	obj.sess = bucketSession(sess, obj.Region, obj.ForcePathStyle)
 
 	return &obj
 }
 
 // Read is a wrapper for an io.Reader
 func (obj *Object) Read(b []byte) (int, error) {
-	if obj.isRead {
-		obj.fetch()
+	if obj.r == nil {
+		if err := obj.Fetch(context.Background()); err != nil {
+			return 0, err
+		}
 	}
 
 	// Trap io.EOF and reset reader (so that the reader is always ready)
 	n, err := obj.r.Read(b)
 	if err == io.EOF {
-		obj.isRead = true
+		obj.r = bytes.NewReader(obj.value)
 	}
 	return n, err
 }
 
-// Close is a wrapper for an io.Closer
+// Close is a wrapper function to meet io.Closer (but is not needed)
 func (obj *Object) Close() error {
-	obj.isRead = true
-	return obj.r.Close()
+	return nil
 }
 
-func (obj *Object) fetch() {
+// Fetch gets the object, whose content is then returned by Read. Once fetched,
+// the object is only downloaded again if its ETag or last modified time
+// changed; otherwise the previous content is kept.
+func (obj *Object) Fetch(ctx context.Context) error {
+	if obj.lookupRegion {
+		sess, err := regionSession(ctx, obj.sess, obj.Bucket)
+		if err != nil {
+			return err
+		}
+		obj.sess = sess
+		obj.lookupRegion = false
+	}
 	s3Svc := awsS3.New(obj.sess)
 
-	result, err := s3Svc.GetObject(&awsS3.GetObjectInput{
+	input := &awsS3.GetObjectInput{
 		Bucket: aws.String(obj.Bucket),
 		Key:    aws.String(obj.Key),
-	})
+	}
+	if obj.VersionID != "" {
+		input.VersionId = aws.String(obj.VersionID)
+	}
+	if obj.value != nil {
+		if obj.etag != "" {
+			input.IfNoneMatch = aws.String(obj.etag)
+		}
+		if !obj.lastModified.IsZero() {
+			input.IfModifiedSince = aws.Time(obj.lastModified)
+		}
+	}
+
+	result, err := s3Svc.GetObjectWithContext(ctx, input)
+	if err != nil {
+		if obj.value != nil && isNotModified(err) {
+			obj.r = bytes.NewReader(obj.value)
+			return nil
+		}
+		return obj.fetchError(err)
+	}
+	defer result.Body.Close()
+
+	value, err := ioutil.ReadAll(result.Body)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case awsS3.ErrCodeNoSuchKey:
-				fmt.Println(awsS3.ErrCodeNoSuchKey, aerr.Error())
-			case awsS3.ErrCodeInvalidObjectState:
-				fmt.Println(awsS3.ErrCodeInvalidObjectState, aerr.Error())
-			default:
-				fmt.Println(aerr.Error())
-			}
-		} else {
-			fmt.Println(err.Error())
+		return internal.Retryable(fmt.Errorf("unable to read object s3://%s/%s: %w", obj.Bucket, obj.Key, err))
+	}
+	if err := verifyETag(value, result); err != nil {
+		return internal.Retryable(fmt.Errorf("object s3://%s/%s: %w", obj.Bucket, obj.Key, err))
+	}
+
+	obj.value = value
+	obj.etag = aws.StringValue(result.ETag)
+	obj.lastModified = aws.TimeValue(result.LastModified)
+	obj.r = bytes.NewReader(obj.value)
+	return nil
+}
+
+func (obj *Object) fetchError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case awsS3.ErrCodeNoSuchKey:
+			return internal.NotFound(fmt.Errorf("object s3://%s/%s not found: %w", obj.Bucket, obj.Key, err))
+		case awsS3.ErrCodeNoSuchBucket:
+			return internal.NotFound(fmt.Errorf("bucket %s not found: %w", obj.Bucket, err))
+		case awsS3.ErrCodeInvalidObjectState:
+			return fmt.Errorf("object s3://%s/%s is archived: %w", obj.Bucket, obj.Key, err)
+		case errCodeSlowDown, errCodeInternalError, errCodeServiceUnavailable:
+			return internal.Retryable(fmt.Errorf("s3 failed to get object s3://%s/%s: %w", obj.Bucket, obj.Key, err))
 		}
 	}
+	return internal.ClassifyAWS(err, fmt.Errorf("unable to get object s3://%s/%s: %w", obj.Bucket, obj.Key, err))
+}
+
+// uriRegion returns the region of the bucket of a URI. The s3:// scheme does
+// not carry a region, so it has to be looked up instead of assuming the
+// default region. Custom endpoints do not have regions of their own.
+func uriRegion(sess *session.Session, u *URI) (region string, lookup bool) {
+	switch {
+	case aws.StringValue(sess.Config.Endpoint) != "":
+		return "", false
+	case StringValue(u.Scheme) == "s3":
+		return "", true
+	default:
+		return StringValue(u.Region), false
+	}
+}
+
+// uriForcePathStyle reports whether a URI is a path style URL of a custom
+// endpoint, which then needs path style requests
+func uriForcePathStyle(sess *session.Session, u *URI) bool {
+	return aws.StringValue(sess.Config.Endpoint) != "" && BoolValue(u.PathStyle)
+}
+
+// bucketSession returns a session to use a client in the same region as the
+// bucket, addressing the bucket in the path if forcePathStyle is set
+func bucketSession(sess *session.Session, region string, forcePathStyle bool) *session.Session {
+	if region != "" && region != aws.StringValue(sess.Config.Region) {
+		sess = sess.Copy(&aws.Config{Region: aws.String(region)})
+	}
+	if forcePathStyle {
+		sess = sess.Copy(&aws.Config{S3ForcePathStyle: aws.Bool(true)})
+	}
+	return sess
+}
+
+// regionSession looks up the region of a bucket, and returns a session in
+// that region
+func regionSession(ctx context.Context, sess *session.Session, bucket string) (*session.Session, error) {
+	hint := aws.StringValue(sess.Config.Region)
+	if hint == "" {
+		hint = DefaultRegion
+	}
 
-	lastModifiedDate := aws.TimeValue(result.LastModified)
-	if lastModifiedDate.After(obj.lastUpdated) {
-		obj.lastUpdated = lastModifiedDate
-		obj.r = result.Body
-		obj.isRead = false
+	region, err := s3manager.GetBucketRegion(ctx, sess, bucket, hint)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == errCodeNotFound {
+			return nil, internal.NotFound(fmt.Errorf("bucket %s not found: %w", bucket, err))
+		}
+		return nil, internal.ClassifyAWS(err, fmt.Errorf("unable to find region of bucket %s: %w", bucket, err))
 	}
+	return bucketSession(sess, region, false), nil
 }
@@ -0,0 +1,282 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awsS3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// Prefix is a seed of every S3 object under a prefix. Each object is an entry
+// named by its key relative to the prefix.
+//
+// Objects are only downloaded again when their ETag changes.
+type Prefix struct {
+	Bucket         string
+	Prefix         string
+	Region         string
+	ForcePathStyle bool
+	Include        []string
+	Exclude        []string
+	Delete         bool
+	sess           *session.Session
+	lookupRegion   bool
+	objects        map[string]prefixObject
+	entries        []internal.Entry
+}
+
+// prefixObject is a downloaded object of a Prefix
+type prefixObject struct {
+	etag  string
+	value []byte
+}
+
+// PrefixOpt is the functional options set for a Prefix
+type PrefixOpt func(*Prefix)
+
+// WithPrefixRegion is a functional option to specify the region of the bucket
+func WithPrefixRegion(s string) PrefixOpt {
+	return func(p *Prefix) {
+		p.Region = s
+	}
+}
+
+// WithPrefixForcePathStyle is a functional option to address the bucket in
+// the path of requests instead of the host
+func WithPrefixForcePathStyle(b bool) PrefixOpt {
+	return func(p *Prefix) {
+		p.ForcePathStyle = b
+	}
+}
+
+// WithPrefixInclude is a functional option to only include objects matching
+// any of the patterns (see MatchPattern)
+func WithPrefixInclude(patterns ...string) PrefixOpt {
+	return func(p *Prefix) {
+		p.Include = append(p.Include, patterns...)
+	}
+}
+
+// WithPrefixExclude is a functional option to exclude objects matching any of
+// the patterns (see MatchPattern)
+func WithPrefixExclude(patterns ...string) PrefixOpt {
+	return func(p *Prefix) {
+		p.Exclude = append(p.Exclude, patterns...)
+	}
+}
+
+// WithPrefixDelete is a functional option to remove entries of objects that
+// no longer exist from the target
+func WithPrefixDelete(b bool) PrefixOpt {
+	return func(p *Prefix) {
+		p.Delete = b
+	}
+}
+
+// NewPrefixFromURI creates a new prefix from a parsed URI, whose key is the
+// prefix. Options override the URI. The region of s3:// URIs is looked up by
+// the first Fetch.
+func NewPrefixFromURI(sess *session.Session, u *URI, opts ...PrefixOpt) *Prefix {
+	var uriOpts []PrefixOpt
+	region, lookup := uriRegion(sess, u)
+	if region != "" {
+		uriOpts = append(uriOpts, WithPrefixRegion(region))
+	}
+	if uriForcePathStyle(sess, u) {
+		uriOpts = append(uriOpts, WithPrefixForcePathStyle(true))
+	}
+
+	p := NewPrefix(sess, StringValue(u.Bucket), StringValue(u.Key), append(uriOpts, opts...)...)
+	p.lookupRegion = lookup && p.Region == ""
+	return p
+}
+
+// NewPrefix creates a new prefix from a bucket and prefix. The prefix is a
+// "directory", so a trailing slash is added if missing.
+func NewPrefix(sess *session.Session, bucket, prefix string, opts ...PrefixOpt) *Prefix {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	p := Prefix{
+		Bucket:  bucket,
+		Prefix:  prefix,
+		sess:    sess,
+		objects: make(map[string]prefixObject),
+	}
+	for _, o := range opts {
+		o(&p)
+	}
+	p.sess = bucketSession(sess, p.Region, p.ForcePathStyle)
+
+	return &p
+}
+
+// MatchPattern reports whether name matches a shell pattern (see path.Match).
+// Patterns without a slash match the base name of name as well, so that
+// "*.pem" matches "certs/ca.pem".
+func MatchPattern(pattern, name string) (bool, error) {
+	if ok, err := path.Match(pattern, name); ok || err != nil {
+		return ok, err
+	}
+	if !strings.Contains(pattern, "/") {
+		return path.Match(pattern, path.Base(name))
+	}
+	return false, nil
+}
+
+// Fetch lists the objects under the prefix, and downloads the objects that
+// changed since the last Fetch. A prefix without any objects is not found.
+func (p *Prefix) Fetch(ctx context.Context) error {
+	if p.lookupRegion {
+		sess, err := regionSession(ctx, p.sess, p.Bucket)
+		if err != nil {
+			return err
+		}
+		p.sess = sess
+		p.lookupRegion = false
+	}
+	s3Svc := awsS3.New(p.sess)
+
+	type listed struct {
+		key, name, etag string
+	}
+	var objects []listed
+	var found bool
+	err := s3Svc.ListObjectsV2PagesWithContext(ctx, &awsS3.ListObjectsV2Input{
+		Bucket: aws.String(p.Bucket),
+		Prefix: aws.String(p.Prefix),
+	}, func(page *awsS3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			found = true
+			key := aws.StringValue(obj.Key)
+			name := strings.TrimPrefix(key, p.Prefix)
+			// Skip "directories" created by the console
+			if name == "" || strings.HasSuffix(name, "/") || !p.match(name) {
+				continue
+			}
+			objects = append(objects, listed{key: key, name: name, etag: aws.StringValue(obj.ETag)})
+		}
+		return true
+	})
+	if err != nil {
+		return p.fetchError(err)
+	}
+	if !found {
+		return internal.NotFound(fmt.Errorf("no objects found under s3://%s/%s", p.Bucket, p.Prefix))
+	}
+
+	downloaded := make(map[string]prefixObject, len(objects))
+	entries := make([]internal.Entry, 0, len(objects))
+	for _, obj := range objects {
+		cached, ok := p.objects[obj.key]
+		if !ok || cached.etag != obj.etag {
+			value, etag, err := p.download(ctx, obj.key)
+			if err != nil {
+				return err
+			}
+			cached = prefixObject{etag: etag, value: value}
+		}
+		downloaded[obj.key] = cached
+		entries = append(entries, internal.Entry{Name: obj.name, Value: cached.value})
+	}
+
+	p.objects = downloaded
+	p.entries = entries
+	return nil
+}
+
+// match reports whether an object is included and not excluded. Patterns are
+// validated by the configuration, so errors do not match.
+func (p *Prefix) match(name string) bool {
+	included := len(p.Include) == 0
+	for _, pattern := range p.Include {
+		if ok, _ := MatchPattern(pattern, name); ok {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, pattern := range p.Exclude {
+		if ok, _ := MatchPattern(pattern, name); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// download gets the content and ETag of an object, verifying the content
+// against the ETag when possible
+func (p *Prefix) download(ctx context.Context, key string) ([]byte, string, error) {
+	s3Svc := awsS3.New(p.sess)
+
+	result, err := s3Svc.GetObjectWithContext(ctx, &awsS3.GetObjectInput{
+		Bucket: aws.String(p.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, "", p.objectError(key, err)
+	}
+	defer result.Body.Close()
+
+	value, err := ioutil.ReadAll(result.Body)
+	if err != nil {
+		return nil, "", internal.Retryable(fmt.Errorf("unable to read object s3://%s/%s: %w", p.Bucket, key, err))
+	}
+	if err := verifyETag(value, result); err != nil {
+		return nil, "", internal.Retryable(fmt.Errorf("object s3://%s/%s: %w", p.Bucket, key, err))
+	}
+	return value, aws.StringValue(result.ETag), nil
+}
+
+// Entries returns the objects of the last Fetch
+func (p *Prefix) Entries() []internal.Entry {
+	return p.entries
+}
+
+// Prune reports whether entries of objects that no longer exist are removed
+func (p *Prefix) Prune() bool {
+	return p.Delete
+}
+
+// Read fails, as the objects under a prefix can only be written as entries
+func (p *Prefix) Read(b []byte) (int, error) {
+	return 0, fmt.Errorf("s3://%s/%s has many objects, which must be written to a directory", p.Bucket, p.Prefix)
+}
+
+// Close is a wrapper function to meet io.Closer (but is not needed)
+func (p *Prefix) Close() error {
+	return nil
+}
+
+func (p *Prefix) fetchError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case awsS3.ErrCodeNoSuchBucket:
+			return internal.NotFound(fmt.Errorf("bucket %s not found: %w", p.Bucket, err))
+		case errCodeSlowDown, errCodeInternalError, errCodeServiceUnavailable:
+			return internal.Retryable(fmt.Errorf("s3 failed to list s3://%s/%s: %w", p.Bucket, p.Prefix, err))
+		}
+	}
+	return internal.ClassifyAWS(err, fmt.Errorf("unable to list s3://%s/%s: %w", p.Bucket, p.Prefix, err))
+}
+
+// objectError marks errors of objects that were deleted after the listing as
+// retryable, so that the prefix is listed again
+func (p *Prefix) objectError(key string, err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case awsS3.ErrCodeNoSuchKey, errCodeSlowDown, errCodeInternalError, errCodeServiceUnavailable:
+			return internal.Retryable(fmt.Errorf("unable to get object s3://%s/%s: %w", p.Bucket, key, err))
+		}
+	}
+	return internal.ClassifyAWS(err, fmt.Errorf("unable to get object s3://%s/%s: %w", p.Bucket, key, err))
+}
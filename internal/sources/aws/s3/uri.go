@@ -66,6 +66,14 @@ func WithRegion(s string) URIOpt {
 	}
 }
 
+// WithEndpoint is a functional options to accept URLs of a custom S3 endpoint
+// (such as MinIO or LocalStack), in path or host style
+func WithEndpoint(s string) URIOpt {
+	return func(uri *URI) {
+		uri.Endpoint = String(s)
+	}
+}
+
 // WithNormalizedKey is a functional options to add a normalized key
 func WithNormalizedKey(b bool) URIOpt {
 	return func(uri *URI) {
@@ -90,6 +98,7 @@ type URI struct {
 	Key       *string
 	VersionID *string
 	Region    *string
+	Endpoint  *string
 }
 
 // NewURI creates a new URI
@@ -204,16 +213,57 @@ func parse(uri *URI, s interface{}) (*URI, error) {
 		}
 		uri.Region = String(DefaultRegion)
 
-		return uri, nil
+		return finalize(uri, u), nil
 	}
 
 	if u.Host == "" {
 		return nil, ErrHostnameNotFound
 	}
 
+	if endpoint := uri.endpointHost(); endpoint != "" && (u.Host == endpoint || strings.HasSuffix(u.Host, "."+endpoint)) {
+		parseEndpoint(uri, u, endpoint)
+	} else if err := parseAWS(uri, u); err != nil {
+		return nil, err
+	}
+
+	return finalize(uri, u), nil
+}
+
+// finalize applies the version ID of the query string, the options and the
+// normalization of the key, which are common to all styles of URI
+func finalize(uri *URI, u *url.URL) *URI {
+	// Query string used when requesting a particular version of a given
+	// S3 object (key).
+	if s := u.Query().Get("versionId"); s != "" {
+		uri.VersionID = String(s)
+	}
+
+	// Apply options that serve as overrides after the initial parsing
+	// is completed.  This allows for bucket name, key, version ID, etc.,
+	// to be overridden at the parsing stage.
+	for _, o := range uri.options {
+		o(uri)
+	}
+
+	// Remove trailing slash from the key name, so that the "key/" will
+	// become "key" and similarly "a/complex/key/" will simply become
+	// "a/complex/key" afer being normalized.
+	if BoolValue(uri.normalize) && uri.Key != nil {
+		k := StringValue(uri.Key)
+		if k[len(k)-1] == '/' {
+			k = k[:len(k)-1]
+		}
+		uri.Key = String(k)
+	}
+
+	return uri
+}
+
+// parseAWS parses the host and path of an AWS S3 endpoint URL
+func parseAWS(uri *URI, u *url.URL) error {
 	matches := s3URLPattern.FindStringSubmatch(u.Host)
 	if matches == nil || len(matches) < 1 {
-		return nil, ErrInvalidS3Endpoint
+		return ErrInvalidS3Endpoint
 	}
 
 	prefix := matches[1]
@@ -221,29 +271,9 @@ func parse(uri *URI, s interface{}) (*URI, error) {
 	region := matches[3]
 
 	if prefix == "" {
-		uri.PathStyle = Bool(true)
-
-		if u.Path != "" && u.Path != "/" {
-			u.Path = u.Path[1:len(u.Path)]
-
-			index := strings.Index(u.Path, "/")
-			switch {
-			case index == -1:
-				uri.Bucket = String(u.Path)
-			case index == len(u.Path)-1:
-				uri.Bucket = String(u.Path[:index])
-			default:
-				uri.Bucket = String(u.Path[:index])
-				uri.Key = String(u.Path[index+1:])
-			}
-		}
+		parsePathStyle(uri, u)
 	} else {
-		uri.HostStyle = Bool(true)
-		uri.Bucket = String(prefix[:len(prefix)-1])
-
-		if u.Path != "" && u.Path != "/" {
-			uri.Key = String(u.Path[1:len(u.Path)])
-		}
+		parseHostStyle(uri, u, prefix[:len(prefix)-1])
 	}
 
 	const (
@@ -255,10 +285,6 @@ func parse(uri *URI, s interface{}) (*URI, error) {
 		// Part of the amazonaws.com domain name.  Set when no region
 		// could be ascertain correctly using the S3 endpoint URL.
 		amazonAWS = "amazonaws"
-
-		// Part of the query parameters.  Used when retrieving S3
-		// object (key) of a particular version.
-		versionID = "versionId"
 	)
 
 	// An S3 bucket can be either accelerated or website endpoint,
@@ -286,36 +312,80 @@ func parse(uri *URI, s interface{}) (*URI, error) {
 		}
 	}
 
-	// Query string used when requesting a particular version of a given
-	// S3 object (key).
-	if s := u.Query().Get(versionID); s != "" {
-		uri.VersionID = String(s)
-	}
+	return nil
+}
 
-	// Apply options that serve as overrides after the initial parsing
-	// is completed.  This allows for bucket name, key, version ID, etc.,
-	// to be overridden at the parsing stage.
-	for _, o := range uri.options {
-		o(uri)
+// parseEndpoint parses the host and path of a custom endpoint URL. The region
+// cannot be determined from a custom endpoint.
+func parseEndpoint(uri *URI, u *url.URL, endpoint string) {
+	if u.Host == endpoint {
+		parsePathStyle(uri, u)
+	} else {
+		parseHostStyle(uri, u, strings.TrimSuffix(u.Host, "."+endpoint))
 	}
+}
 
-	// Remove trailing slash from the key name, so that the "key/" will
-	// become "key" and similarly "a/complex/key/" will simply become
-	// "a/complex/key" afer being normalized.
-	if BoolValue(uri.normalize) && uri.Key != nil {
-		k := StringValue(uri.Key)
-		if k[len(k)-1] == '/' {
-			k = k[:len(k)-1]
+// parsePathStyle parses the bucket and key from the path of the URL
+func parsePathStyle(uri *URI, u *url.URL) {
+	uri.PathStyle = Bool(true)
+
+	if u.Path != "" && u.Path != "/" {
+		u.Path = u.Path[1:len(u.Path)]
+
+		index := strings.Index(u.Path, "/")
+		switch {
+		case index == -1:
+			uri.Bucket = String(u.Path)
+		case index == len(u.Path)-1:
+			uri.Bucket = String(u.Path[:index])
+		default:
+			uri.Bucket = String(u.Path[:index])
+			uri.Key = String(u.Path[index+1:])
 		}
-		uri.Key = String(k)
 	}
+}
+
+// parseHostStyle parses the key from the path of the URL, with the bucket in
+// the host
+func parseHostStyle(uri *URI, u *url.URL, bucket string) {
+	uri.HostStyle = Bool(true)
+	uri.Bucket = String(bucket)
+
+	if u.Path != "" && u.Path != "/" {
+		uri.Key = String(u.Path[1:len(u.Path)])
+	}
+}
+
+// endpointHost returns the host of the custom endpoint set by the options
+func (uri *URI) endpointHost() string {
+	var probe URI
+	for _, o := range uri.options {
+		o(&probe)
+	}
+	return EndpointHost(StringValue(probe.Endpoint))
+}
 
-	return uri, nil
+// EndpointHost returns the host (and port) of an endpoint, which may be given
+// with or without a scheme
+func EndpointHost(endpoint string) string {
+	if endpoint == "" {
+		return ""
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	return u.Host
 }
 
-// Reset fields in the URI type, and set boolean values to false.
+// Reset fields in the URI type, and set boolean values to false. The options
+// are kept, so that they apply to the next parse.
 func reset(uri *URI) *URI {
 	*uri = URI{
+		options:     uri.options,
 		HostStyle:   Bool(false),
 		PathStyle:   Bool(false),
 		Accelerated: Bool(false),
@@ -0,0 +1,140 @@
+package s3
+
+import (
+	"fmt"
+	"testing"
+)
+
+// describe summarizes the parsed fields of a URI
+func describe(uri *URI) string {
+	key := "<nil>"
+	if uri.Key != nil {
+		key = fmt.Sprintf("%q", StringValue(uri.Key))
+	}
+	style := "s3"
+	switch {
+	case BoolValue(uri.PathStyle):
+		style = "path"
+	case BoolValue(uri.HostStyle):
+		style = "host"
+	}
+	return fmt.Sprintf("bucket=%s key=%s region=%s version=%s style=%s",
+		StringValue(uri.Bucket), key, StringValue(uri.Region), StringValue(uri.VersionID), style)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		uri  string
+		opts []URIOpt
+		want string
+	}{
+		// AWS
+		{uri: "https://bucket.s3.us-west-2.amazonaws.com/a/b", want: `bucket=bucket key="a/b" region=us-west-2 version= style=host`},
+		{uri: "https://s3.us-west-2.amazonaws.com/bucket/a/b", want: `bucket=bucket key="a/b" region=us-west-2 version= style=path`},
+		{uri: "https://s3-eu-west-1.amazonaws.com/bucket/", want: `bucket=bucket key=<nil> region=eu-west-1 version= style=path`},
+		{uri: "https://bucket.s3.amazonaws.com/key", want: `bucket=bucket key="key" region=us-east-1 version= style=host`},
+		{uri: "https://bucket.s3.us-west-2.amazonaws.com/key?versionId=v1", want: `bucket=bucket key="key" region=us-west-2 version=v1 style=host`},
+		{
+			uri:  "https://bucket.s3.us-west-2.amazonaws.com/key",
+			opts: []URIOpt{WithEndpoint("http://localhost:9000")},
+			want: `bucket=bucket key="key" region=us-west-2 version= style=host`,
+		},
+
+		// s3://
+		{uri: "s3://bucket/a/b", want: `bucket=bucket key="a/b" region=us-east-1 version= style=s3`},
+		{uri: "s3://bucket", want: `bucket=bucket key=<nil> region=us-east-1 version= style=s3`},
+		{uri: "s3://bucket/key?versionId=3", want: `bucket=bucket key="key" region=us-east-1 version=3 style=s3`},
+		{uri: "s3://bucket/prefix/", want: `bucket=bucket key="prefix/" region=us-east-1 version= style=s3`},
+		{uri: "s3://bucket/prefix/", opts: []URIOpt{WithNormalizedKey(true)}, want: `bucket=bucket key="prefix" region=us-east-1 version= style=s3`},
+		{uri: "s3://bucket//", opts: []URIOpt{WithNormalizedKey(true)}, want: `bucket=bucket key="" region=us-east-1 version= style=s3`},
+		{uri: "s3://bucket/key", opts: []URIOpt{WithVersionID("override")}, want: `bucket=bucket key="key" region=us-east-1 version=override style=s3`},
+
+		// Custom endpoints
+		{
+			uri:  "http://localhost:9000/bucket/a/b",
+			opts: []URIOpt{WithEndpoint("http://localhost:9000")},
+			want: `bucket=bucket key="a/b" region= version= style=path`,
+		},
+		{
+			uri:  "http://localhost:9000/bucket/a/b",
+			opts: []URIOpt{WithEndpoint("localhost:9000")},
+			want: `bucket=bucket key="a/b" region= version= style=path`,
+		},
+		{
+			uri:  "http://bucket.localhost:9000/a/b",
+			opts: []URIOpt{WithEndpoint("http://localhost:9000/")},
+			want: `bucket=bucket key="a/b" region= version= style=host`,
+		},
+		{
+			uri:  "http://localhost:9000/bucket/key?versionId=v2",
+			opts: []URIOpt{WithEndpoint("localhost:9000")},
+			want: `bucket=bucket key="key" region= version=v2 style=path`,
+		},
+		{
+			uri:  "http://localhost:9000/bucket/prefix/",
+			opts: []URIOpt{WithEndpoint("localhost:9000"), WithNormalizedKey(true)},
+			want: `bucket=bucket key="prefix" region= version= style=path`,
+		},
+	}
+	for _, tt := range tests {
+		uri, err := NewURI(tt.opts...).ParseString(tt.uri)
+		if err != nil {
+			t.Errorf("ParseString(%q) error = %v", tt.uri, err)
+			continue
+		}
+		if got := describe(uri); got != tt.want {
+			t.Errorf("ParseString(%q) = %s, want %s", tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		uri  string
+		opts []URIOpt
+		want error
+	}{
+		{uri: "s3:///key", want: ErrBucketNotFound},
+		{uri: "https:///key", want: ErrHostnameNotFound},
+		{uri: "http://localhost:9000/bucket/key", want: ErrInvalidS3Endpoint},
+		{uri: "http://localhost:9001/bucket/key", opts: []URIOpt{WithEndpoint("localhost:9000")}, want: ErrInvalidS3Endpoint},
+		{uri: "http://evil-localhost:9000/bucket/key", opts: []URIOpt{WithEndpoint("localhost:9000")}, want: ErrInvalidS3Endpoint},
+	}
+	for _, tt := range tests {
+		if _, err := NewURI(tt.opts...).ParseString(tt.uri); err != tt.want {
+			t.Errorf("ParseString(%q) error = %v, want %v", tt.uri, err, tt.want)
+		}
+	}
+
+	if _, err := ParseString("ftp://bucket/key"); err == nil {
+		t.Error("ParseString() of an unknown scheme succeeded")
+	}
+}
+
+// Options apply to every parse of the same URI, not only the first
+func TestParseKeepsOptions(t *testing.T) {
+	uri := NewURI(WithEndpoint("localhost:9000"))
+	for i := 0; i < 2; i++ {
+		if _, err := uri.ParseString("http://localhost:9000/bucket/key"); err != nil {
+			t.Fatalf("parse %d error = %v", i+1, err)
+		}
+	}
+	if uri.Reset(); StringValue(uri.Endpoint) != "" {
+		t.Errorf("Endpoint = %q after Reset, want it to be set by the next parse", StringValue(uri.Endpoint))
+	}
+}
+
+func TestEndpointHost(t *testing.T) {
+	tests := map[string]string{
+		"":                        "",
+		"localhost:9000":          "localhost:9000",
+		"http://localhost:4566":   "localhost:4566",
+		"https://minio.internal/": "minio.internal",
+		"s3.example.com":          "s3.example.com",
+	}
+	for endpoint, want := range tests {
+		if got := EndpointHost(endpoint); got != want {
+			t.Errorf("EndpointHost(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package secretsmanager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// Exploded represents a seed that sources from an AWS Secrets Manager secret
+// holding a JSON object. Each top-level field is an entry named by its key.
+type Exploded struct {
+	*Secret
+	entries []internal.Entry
+}
+
+// NewExploded creates a new Exploded seed. With WithSecretKey, the fields of
+// the selected object are used instead.
+func NewExploded(sess *session.Session, name string, opts ...SecretOpt) *Exploded {
+	return &Exploded{
+		Secret: NewSecret(sess, name, opts...),
+	}
+}
+
+// Fetch gets the latest value of the secret, and splits it into entries
+func (e *Exploded) Fetch(ctx context.Context) error {
+	if err := e.Secret.Fetch(ctx); err != nil {
+		return err
+	}
+
+	entries, err := explode(e.Secret.value)
+	if err != nil {
+		return fmt.Errorf("secret %s: %w", e.Name, err)
+	}
+	e.entries = entries
+	return nil
+}
+
+// Entries returns the fields of the last Fetch
+func (e *Exploded) Entries() []internal.Entry {
+	return e.entries
+}
+
+// Read fails, as the fields of the secret can only be written as entries
+func (e *Exploded) Read(b []byte) (int, error) {
+	return 0, fmt.Errorf("secret %s has many fields, which must be written to a directory", e.Name)
+}
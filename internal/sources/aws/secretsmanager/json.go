@@ -0,0 +1,144 @@
+package secretsmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// keyPart is a part of a key, either the name of a field or an index in an
+// array
+type keyPart struct {
+	name  string
+	index int
+}
+
+// ValidateKey checks the syntax of a key, which selects a field of a JSON
+// document: field names separated by dots, and array indexes in brackets, e.g.
+// "db.hosts[0]". A leading "$." is optional.
+func ValidateKey(key string) error {
+	_, err := parseKey(key)
+	return err
+}
+
+func parseKey(key string) ([]keyPart, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(key, "$"), ".")
+	if s == "" {
+		return nil, fmt.Errorf("invalid key %q", key)
+	}
+
+	var parts []keyPart
+	for s != "" {
+		switch {
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid key %q: missing ]", key)
+			}
+			index, err := strconv.Atoi(s[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid key %q: invalid index %q", key, s[1:end])
+			}
+			parts = append(parts, keyPart{index: index})
+			s = s[end+1:]
+		default:
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid key %q: empty field name", key)
+			}
+			parts = append(parts, keyPart{name: s[:end], index: -1})
+			s = s[end:]
+		}
+		if strings.HasPrefix(s, ".") {
+			s = s[1:]
+			if s == "" || s[0] == '.' || s[0] == '[' {
+				return nil, fmt.Errorf("invalid key %q: empty field name", key)
+			}
+		}
+	}
+	return parts, nil
+}
+
+// parseDocument parses the value of a secret as a JSON document
+func parseDocument(value []byte) (interface{}, error) {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("secret is not a JSON document: %w", err)
+	}
+	return doc, nil
+}
+
+// selectKey returns the field of a JSON document selected by key
+func selectKey(value []byte, key string) ([]byte, error) {
+	parts, err := parseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseDocument(value)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, part := range parts {
+		if part.index >= 0 {
+			arr, ok := doc.([]interface{})
+			if !ok || part.index >= len(arr) {
+				return nil, internal.NotFound(fmt.Errorf("key %s not found", key))
+			}
+			doc = arr[part.index]
+			continue
+		}
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, internal.NotFound(fmt.Errorf("key %s not found", key))
+		}
+		if doc, ok = obj[part.name]; !ok {
+			return nil, internal.NotFound(fmt.Errorf("key %s not found", key))
+		}
+	}
+	return jsonValue(doc)
+}
+
+// explode returns every top-level field of a JSON document as an entry
+func explode(value []byte) ([]internal.Entry, error) {
+	doc, err := parseDocument(value)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("secret is not a JSON object")
+	}
+
+	entries := make([]internal.Entry, 0, len(obj))
+	for name, field := range obj {
+		b, err := jsonValue(field)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, internal.Entry{Name: name, Value: b, Secret: true})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// jsonValue returns strings as they are, and any other value as JSON
+func jsonValue(v interface{}) ([]byte, error) {
+	if s, ok := v.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(v)
+}
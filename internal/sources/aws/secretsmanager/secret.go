@@ -1,85 +1,195 @@
 package secretsmanager
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/buzzsurfr/seeder/internal"
 )
 
-// Secret represents a seed that sources from an AWS Secrets Manager secret
+// EncodingBase64 is the Encoding of a secret stored base64-encoded as a string
+const EncodingBase64 = "base64"
+
+// Secret represents a seed that sources from an AWS Secrets Manager secret.
+// Without a VersionStage or VersionID, the AWSCURRENT version is used.
+//
+// The value is the binary secret if there is one, or else the string secret.
+// With an Encoding, the string secret is decoded first. With a Key, only that
+// field of the JSON document in the secret is used.
 type Secret struct {
-	Name        string
-	value       string
-	sess        *session.Session
-	r           io.ReadCloser
-	lastUpdated time.Time
+	Name         string
+	VersionStage string
+	VersionID    string
+	Encoding     string
+	Key          string
+	value        []byte
+	versionID    string
+	sess         *session.Session
+	r            io.ReadCloser
+}
+
+// SecretOpt is the functional options set for a Secret
+type SecretOpt func(*Secret)
+
+// WithSecretVersionStage is a functional option to get the version of the
+// secret with a staging label, such as AWSPENDING
+func WithSecretVersionStage(stage string) SecretOpt {
+	return func(s *Secret) {
+		s.VersionStage = stage
+	}
+}
+
+// WithSecretVersionID is a functional option to get a specific version of the
+// secret
+func WithSecretVersionID(id string) SecretOpt {
+	return func(s *Secret) {
+		s.VersionID = id
+	}
+}
+
+// WithSecretEncoding is a functional option to decode a string secret, such
+// as EncodingBase64
+func WithSecretEncoding(encoding string) SecretOpt {
+	return func(s *Secret) {
+		s.Encoding = encoding
+	}
+}
+
+// WithSecretKey is a functional option to select a field of a secret that is
+// a JSON document (see ValidateKey)
+func WithSecretKey(key string) SecretOpt {
+	return func(s *Secret) {
+		s.Key = key
+	}
 }
 
 // NewSecret creates a new Secret seed
-func NewSecret(sess *session.Session, name string) *Parameter {
-	secret := Secret{
+func NewSecret(sess *session.Session, name string, opts ...SecretOpt) *Secret {
+	s := Secret{
 		Name: name,
 		sess: sess,
 	}
-	secret.fetch()
+	for _, o := range opts {
+		o(&s)
+	}
+
+	return &s
+}
+
+// Fetch gets the latest value of the secret, which is then returned by Read
+func (s *Secret) Fetch(ctx context.Context) error {
+	secretsmanagerSvc := secretsmanager.New(s.sess)
+
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(s.Name),
+	}
+	if s.VersionStage != "" {
+		input.VersionStage = aws.String(s.VersionStage)
+	}
+	if s.VersionID != "" {
+		input.VersionId = aws.String(s.VersionID)
+	}
+	result, err := secretsmanagerSvc.GetSecretValueWithContext(ctx, input)
+	if err != nil {
+		return s.fetchError(err)
+	}
 
-	return &param
+	// The value of a version never changes
+	if versionID := aws.StringValue(result.VersionId); versionID != s.versionID || s.value == nil {
+		value, err := s.decode(result)
+		if err != nil {
+			return err
+		}
+		s.value = value
+		s.versionID = versionID
+	}
+	s.r = ioutil.NopCloser(bytes.NewReader(s.value))
+
+	return nil
 }
 
 func (s *Secret) Read(b []byte) (int, error) {
-	s.fetch()
+	if s.r == nil {
+		if err := s.Fetch(context.Background()); err != nil {
+			return 0, err
+		}
+	}
 
 	// Trap io.EOF and reset reader (so that the reader is always ready)
 	n, err := s.r.Read(b)
 	if err == io.EOF {
-		s.r = ioutil.NopCloser(strings.NewReader(s.value))
+		s.r = ioutil.NopCloser(bytes.NewReader(s.value))
 	}
 	return n, err
 }
 
+// decode returns the value of a fetched secret
+func (s *Secret) decode(result *secretsmanager.GetSecretValueOutput) ([]byte, error) {
+	value := result.SecretBinary
+	if value == nil {
+		str := aws.StringValue(result.SecretString)
+		switch s.Encoding {
+		case "":
+			value = []byte(str)
+		case EncodingBase64:
+			b, err := base64.StdEncoding.DecodeString(str)
+			if err != nil {
+				return nil, fmt.Errorf("unable to decode secret %s: %w", s.Name, err)
+			}
+			value = b
+		default:
+			return nil, fmt.Errorf("unknown encoding %q for secret %s", s.Encoding, s.Name)
+		}
+	}
+
+	if s.Key == "" {
+		return value, nil
+	}
+	field, err := selectKey(value, s.Key)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", s.Name, err)
+	}
+	return field, nil
+}
+
+// Version returns the version ID of the secret that was last fetched
+func (s *Secret) Version() string {
+	return s.versionID
+}
+
+// IsSecret reports true, as the value of a secret is always secret
+func (s *Secret) IsSecret() bool {
+	return true
+}
+
 // Close is a wrapper function to meet io.Closer (but is not needed)
 func (s *Secret) Close() error {
+	if s.r == nil {
+		return nil
+	}
 	return s.r.Close()
 }
 
-func (s *Secret) fetch() {
-	secretsmanagerSvc := secretsmanager.New(s.sess)
-
-	result, err := secretsmanagerSvc.GetSecretValue(&secretsmanager.GetSecretValueInput{
-		Name:           aws.String(s.Name),
-	})
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case secretsmanager.ErrCodeResourceNotFoundException:
-				fmt.Println(secretsmanager.ErrCodeResourceNotFoundException, aerr.Error())
-			case secretsmanager.ErrCodeInvalidParameterException:
-				fmt.Println(secretsmanager.ErrCodeInvalidParameterException, aerr.Error())
-			case secretsmanager.ErrCodeInvalidRequestException:
-				fmt.Println(secretsmanager.ErrCodeInvalidRequestException, aerr.Error())
-			case secretsmanager.ErrCodeDecryptionFailure:
-				fmt.Println(secretsmanager.ErrCodeDecryptionFailure, aerr.Error())
-			case secretsmanager.ErrCodeInternalServiceError:
-				fmt.Println(secretsmanager.ErrCodeInternalServiceError, aerr.Error())
-			default:
-				fmt.Println(aerr.Error())
-			}
-		} else {
-			fmt.Println(err.Error())
+func (s *Secret) fetchError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case secretsmanager.ErrCodeResourceNotFoundException:
+			return internal.NotFound(fmt.Errorf("secret %s not found: %w", s.Name, err))
+		case secretsmanager.ErrCodeInvalidParameterException, secretsmanager.ErrCodeInvalidRequestException:
+			return fmt.Errorf("invalid request for secret %s: %w", s.Name, err)
+		case secretsmanager.ErrCodeDecryptionFailure:
+			return fmt.Errorf("unable to decrypt secret %s: %w", s.Name, err)
+		case secretsmanager.ErrCodeInternalServiceError:
+			return internal.Retryable(fmt.Errorf("secrets manager failed to get secret %s: %w", s.Name, err))
 		}
 	}
-
-	lastModifiedDate := aws.TimeValue(result.CreatedDate)
-	if lastModifiedDate.After(s.lastUpdated) {
-		s.value = aws.StringValue(result.SecretString)
-		s.lastUpdated = lastModifiedDate
-		s.r = ioutil.NopCloser(strings.NewReader(s.value))
-	}
+	return internal.ClassifyAWS(err, fmt.Errorf("unable to get secret %s: %w", s.Name, err))
 }
@@ -0,0 +1,81 @@
+package ssm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awsSsm "github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// MaxBatchSize is the maximum number of names in a GetParameters request
+const MaxBatchSize = 10
+
+// FetchParameters gets the latest values of many parameters with as few
+// GetParameters requests as possible. The value (or error) of each parameter is
+// returned by its next Fetch, so that the parameters do not have to be fetched
+// one by one. Parameters of a failed request are left to be fetched by their
+// next Fetch.
+func FetchParameters(ctx context.Context, params []*Parameter) {
+	// Parameters can only be fetched together with the same session
+	var sessions []*session.Session
+	groups := make(map[*session.Session][]*Parameter)
+	for _, param := range params {
+		if _, ok := groups[param.sess]; !ok {
+			sessions = append(sessions, param.sess)
+		}
+		groups[param.sess] = append(groups[param.sess], param)
+	}
+
+	for _, sess := range sessions {
+		fetchParameters(ctx, sess, groups[sess])
+	}
+}
+
+func fetchParameters(ctx context.Context, sess *session.Session, params []*Parameter) {
+	ssmSvc := awsSsm.New(sess)
+
+	// Several seeds can use the same parameter
+	var names []string
+	byName := make(map[string][]*Parameter)
+	for _, param := range params {
+		name := param.selected()
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], param)
+	}
+
+	for start := 0; start < len(names); start += MaxBatchSize {
+		end := start + MaxBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+		batch := names[start:end]
+
+		result, err := ssmSvc.GetParametersWithContext(ctx, &awsSsm.GetParametersInput{
+			Names:          aws.StringSlice(batch),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			// The error may be caused by any parameter of the batch (such as
+			// one that cannot be read), so each parameter gets itself instead
+			continue
+		}
+
+		for _, p := range result.Parameters {
+			// Parameters fetched with a selector are returned without it
+			name := aws.StringValue(p.Name) + aws.StringValue(p.Selector)
+			for _, param := range byName[name] {
+				param.prefetch(p, nil)
+			}
+		}
+		for _, name := range aws.StringValueSlice(result.InvalidParameters) {
+			for _, param := range byName[name] {
+				param.prefetch(nil, internal.NotFound(fmt.Errorf("parameter %s not found", name)))
+			}
+		}
+	}
+}
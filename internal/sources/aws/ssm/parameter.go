@@ -1,40 +1,121 @@
 package ssm
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	awsSsm "github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/buzzsurfr/seeder/internal"
 )
 
-// Parameter represents a seed that sources from an AWS SSM Parameter
+// Parameter represents a seed that sources from an AWS SSM Parameter. The
+// Selector (":version" or ":label") pins the parameter to a version, instead of
+// the latest version.
 type Parameter struct {
-	Name        string
-	value       string
-	sess        *session.Session
-	r           io.ReadCloser
-	lastUpdated time.Time
+	Name     string
+	Selector string
+	value    string
+	secure   bool
+	version  int64
+	sess     *session.Session
+	r        io.ReadCloser
+
+	// Result of FetchParameters, returned by the next Fetch
+	prefetched  bool
+	prefetchErr error
+}
+
+// ParameterOpt is the functional options set for a Parameter
+type ParameterOpt func(*Parameter)
+
+// WithParameterVersion is a functional option to get a specific version of
+// the parameter
+func WithParameterVersion(version int64) ParameterOpt {
+	return func(param *Parameter) {
+		param.Selector = ":" + strconv.FormatInt(version, 10)
+	}
+}
+
+// WithParameterLabel is a functional option to get the version of the
+// parameter with a label
+func WithParameterLabel(label string) ParameterOpt {
+	return func(param *Parameter) {
+		param.Selector = ":" + label
+	}
 }
 
 // NewParameter creates a new Parameter seed
-func NewParameter(sess *session.Session, name string) *Parameter {
+func NewParameter(sess *session.Session, name string, opts ...ParameterOpt) *Parameter {
 	param := Parameter{
 		Name: name,
 		sess: sess,
 	}
-	param.fetch()
+	for _, o := range opts {
+		o(&param)
+	}
 
 	return &param
 }
 
+// selected is the name of the parameter, including the selector
+func (param *Parameter) selected() string {
+	return param.Name + param.Selector
+}
+
+// Fetch gets the latest value of the parameter, which is then returned by Read.
+// If the parameter was just fetched by FetchParameters, that result is used
+// instead.
+func (param *Parameter) Fetch(ctx context.Context) error {
+	if param.prefetched {
+		param.prefetched = false
+		return param.prefetchErr
+	}
+
+	ssmSvc := awsSsm.New(param.sess)
+
+	result, err := ssmSvc.GetParameterWithContext(ctx, &awsSsm.GetParameterInput{
+		Name:           aws.String(param.selected()),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return param.fetchError(err)
+	}
+	param.update(result.Parameter)
+
+	return nil
+}
+
+// update sets the value of the parameter from a fetched parameter. A label
+// can move to an older version, so the fetched version always wins.
+func (param *Parameter) update(p *awsSsm.Parameter) {
+	param.value = aws.StringValue(p.Value)
+	param.secure = aws.StringValue(p.Type) == awsSsm.ParameterTypeSecureString
+	param.version = aws.Int64Value(p.Version)
+	param.r = ioutil.NopCloser(strings.NewReader(param.value))
+}
+
+// prefetch stores the result of FetchParameters for the next Fetch
+func (param *Parameter) prefetch(p *awsSsm.Parameter, err error) {
+	if err == nil {
+		param.update(p)
+	}
+	param.prefetched = true
+	param.prefetchErr = err
+}
+
 func (param *Parameter) Read(b []byte) (int, error) {
-	param.fetch()
+	if param.r == nil {
+		if err := param.Fetch(context.Background()); err != nil {
+			return 0, err
+		}
+	}
 
 	// Trap io.EOF and reset reader (so that the reader is always ready)
 	n, err := param.r.Read(b)
@@ -44,39 +125,39 @@ func (param *Parameter) Read(b []byte) (int, error) {
 	return n, err
 }
 
+// Version returns the version of the parameter that was last fetched
+func (param *Parameter) Version() string {
+	if param.version == 0 {
+		return ""
+	}
+	return strconv.FormatInt(param.version, 10)
+}
+
+// IsSecret reports whether the parameter is a SecureString
+func (param *Parameter) IsSecret() bool {
+	return param.secure
+}
+
 // Close is a wrapper function to meet io.Closer (but is not needed)
 func (param *Parameter) Close() error {
+	if param.r == nil {
+		return nil
+	}
 	return param.r.Close()
 }
 
-func (param *Parameter) fetch() {
-	ssmSvc := awsSsm.New(param.sess)
-
-	result, err := ssmSvc.GetParameter(&awsSsm.GetParameterInput{
-		Name:           aws.String(param.Name),
-		WithDecryption: aws.Bool(true),
-	})
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case awsSsm.ErrCodeInvalidKeyId:
-				fmt.Println(awsSsm.ErrCodeInvalidKeyId, aerr.Error())
-			case awsSsm.ErrCodeParameterNotFound:
-				fmt.Println(awsSsm.ErrCodeParameterNotFound, aerr.Error())
-			case awsSsm.ErrCodeParameterVersionNotFound:
-				fmt.Println(awsSsm.ErrCodeParameterVersionNotFound, aerr.Error())
-			default:
-				fmt.Println(aerr.Error())
-			}
-		} else {
-			fmt.Println(err.Error())
+func (param *Parameter) fetchError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case awsSsm.ErrCodeInvalidKeyId:
+			return fmt.Errorf("parameter %s has an invalid KMS key: %w", param.selected(), err)
+		case awsSsm.ErrCodeParameterNotFound:
+			return internal.NotFound(fmt.Errorf("parameter %s not found: %w", param.selected(), err))
+		case awsSsm.ErrCodeParameterVersionNotFound:
+			return internal.NotFound(fmt.Errorf("parameter %s version not found: %w", param.selected(), err))
+		case awsSsm.ErrCodeInternalServerError:
+			return internal.Retryable(fmt.Errorf("parameter store failed to get parameter %s: %w", param.selected(), err))
 		}
 	}
-
-	lastModifiedDate := aws.TimeValue(result.Parameter.LastModifiedDate)
-	if lastModifiedDate.After(param.lastUpdated) {
-		param.value = aws.StringValue(result.Parameter.Value)
-		param.lastUpdated = lastModifiedDate
-		param.r = ioutil.NopCloser(strings.NewReader(param.value))
-	}
+	return internal.ClassifyAWS(err, fmt.Errorf("unable to get parameter %s: %w", param.selected(), err))
 }
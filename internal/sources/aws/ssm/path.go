@@ -0,0 +1,102 @@
+package ssm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awsSsm "github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// Path represents a seed that sources from every AWS SSM Parameter under a
+// path. Each parameter is an entry named by its name relative to the path.
+type Path struct {
+	Path    string
+	sess    *session.Session
+	entries []internal.Entry
+}
+
+// NewPath creates a new Path seed
+func NewPath(sess *session.Session, path string) *Path {
+	return &Path{
+		Path: path,
+		sess: sess,
+	}
+}
+
+// Fetch gets the latest values of all parameters under the path, recursively.
+// A path without any parameters is not found.
+func (p *Path) Fetch(ctx context.Context) error {
+	ssmSvc := awsSsm.New(p.sess)
+
+	prefix := strings.TrimSuffix(p.Path, "/") + "/"
+	var entries []internal.Entry
+	err := ssmSvc.GetParametersByPathPagesWithContext(ctx, &awsSsm.GetParametersByPathInput{
+		Path:           aws.String(p.Path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	}, func(page *awsSsm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, param := range page.Parameters {
+			entries = append(entries, internal.Entry{
+				Name:   strings.TrimPrefix(aws.StringValue(param.Name), prefix),
+				Value:  []byte(aws.StringValue(param.Value)),
+				Secret: aws.StringValue(param.Type) == awsSsm.ParameterTypeSecureString,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return p.fetchError(err)
+	}
+	if len(entries) == 0 {
+		return internal.NotFound(fmt.Errorf("no parameters found under path %s", p.Path))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	p.entries = entries
+	return nil
+}
+
+// Entries returns the parameters of the last Fetch
+func (p *Path) Entries() []internal.Entry {
+	return p.entries
+}
+
+// Read fails, as the parameters of a path can only be written as entries
+func (p *Path) Read(b []byte) (int, error) {
+	return 0, fmt.Errorf("path %s has many parameters, which must be written to a directory", p.Path)
+}
+
+// IsSecret reports whether any of the parameters is a SecureString
+func (p *Path) IsSecret() bool {
+	for _, e := range p.entries {
+		if e.Secret {
+			return true
+		}
+	}
+	return false
+}
+
+// Close is a wrapper function to meet io.Closer (but is not needed)
+func (p *Path) Close() error {
+	return nil
+}
+
+func (p *Path) fetchError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case awsSsm.ErrCodeInvalidKeyId:
+			return fmt.Errorf("parameters under path %s have an invalid KMS key: %w", p.Path, err)
+		case awsSsm.ErrCodeInternalServerError:
+			return internal.Retryable(fmt.Errorf("parameter store failed to get parameters under path %s: %w", p.Path, err))
+		}
+	}
+	return internal.ClassifyAWS(err, fmt.Errorf("unable to get parameters under path %s: %w", p.Path, err))
+}
@@ -0,0 +1,62 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// File represents a seed that sources from a local file, such as a file baked
+// into an image
+type File struct {
+	Path  string
+	value []byte
+	r     io.Reader
+}
+
+// NewFile creates a new File seed
+func NewFile(path string) *File {
+	return &File{
+		Path: path,
+	}
+}
+
+// Fetch reads the file, which is then returned by Read
+func (f *File) Fetch(ctx context.Context) error {
+	value, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return internal.NotFound(fmt.Errorf("file %s not found: %w", f.Path, err))
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read file %s: %w", f.Path, err)
+	}
+
+	f.value = value
+	f.r = bytes.NewReader(f.value)
+	return nil
+}
+
+func (f *File) Read(b []byte) (int, error) {
+	if f.r == nil {
+		if err := f.Fetch(context.Background()); err != nil {
+			return 0, err
+		}
+	}
+
+	// Trap io.EOF and reset reader (so that the reader is always ready)
+	n, err := f.r.Read(b)
+	if err == io.EOF {
+		f.r = bytes.NewReader(f.value)
+	}
+	return n, err
+}
+
+// Close is a wrapper function to meet io.Closer (but is not needed)
+func (f *File) Close() error {
+	return nil
+}
@@ -0,0 +1,41 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// Value represents a seed that sources from a value in the configuration
+type Value struct {
+	value []byte
+	r     io.Reader
+}
+
+// NewValue creates a new Value seed
+func NewValue(value []byte) *Value {
+	return &Value{
+		value: value,
+		r:     bytes.NewReader(value),
+	}
+}
+
+// Fetch resets the value, so that it can be read again
+func (v *Value) Fetch(ctx context.Context) error {
+	v.r = bytes.NewReader(v.value)
+	return nil
+}
+
+func (v *Value) Read(b []byte) (int, error) {
+	// Trap io.EOF and reset reader (so that the reader is always ready)
+	n, err := v.r.Read(b)
+	if err == io.EOF {
+		v.r = bytes.NewReader(v.value)
+	}
+	return n, err
+}
+
+// Close is a wrapper function to meet io.Closer (but is not needed)
+func (v *Value) Close() error {
+	return nil
+}
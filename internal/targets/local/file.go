@@ -1,65 +1,290 @@
 package local
 
 import (
-	"fmt"
+	"bytes"
+	"crypto/sha256"
+	"hash"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+const (
+	// DefaultMode is the mode of a file when none is set
+	DefaultMode os.FileMode = 0644
+	// DefaultSecretMode is the mode of a file when none is set and the source
+	// is a secret
+	DefaultSecretMode os.FileMode = 0600
+	// DefaultDirMode is the mode of created directories when none is set
+	DefaultDirMode os.FileMode = 0755
 )
 
 // File is a local file seed
+//
+// Content is written to a temporary file in the same directory and renamed
+// into place once it has been written completely, so that readers never see a
+// partially written file and a failed write leaves the previous file intact.
+// If the content is identical to the existing file, the file is left as is.
 type File struct {
-	Path      string
-	Name      string
-	file      *os.File
-	w         io.WriteCloser
-	isWritten bool
+	Path    string
+	Name    string
+	Mode    os.FileMode
+	DirMode os.FileMode
+	UID     int
+	GID     int
+	tmp     *os.File
+	hash    hash.Hash
+	err     error
+	secret  bool
+	changed bool
+}
+
+// FileOpt is the functional options set for a File
+type FileOpt func(*File)
+
+// WithMode is a functional option to set the mode of the file
+func WithMode(mode os.FileMode) FileOpt {
+	return func(f *File) {
+		f.Mode = mode
+	}
+}
+
+// WithDirMode is a functional option to set the mode of created directories
+func WithDirMode(mode os.FileMode) FileOpt {
+	return func(f *File) {
+		f.DirMode = mode
+	}
+}
+
+// WithOwner is a functional option to set the owner and group of the file.
+// An id of -1 leaves it unchanged.
+func WithOwner(uid, gid int) FileOpt {
+	return func(f *File) {
+		f.UID = uid
+		f.GID = gid
+	}
 }
 
 // NewFile creates a new local file
-func NewFile(path, name string) *File {
-	lf := File{
-		Path: path,
-		Name: name,
+func NewFile(path, name string, opts ...FileOpt) *File {
+	f := File{
+		Path:    path,
+		Name:    name,
+		DirMode: DefaultDirMode,
+		UID:     -1,
+		GID:     -1,
+	}
+	for _, o := range opts {
+		o(&f)
 	}
-	lf.initialize()
 
-	return &lf
+	return &f
 }
 
-// Write is a wrapper for an io.Writer
+// Write is a wrapper for an io.Writer. The content is only moved into place
+// when the File is closed.
 func (f *File) Write(p []byte) (int, error) {
-	if f.isWritten {
-		f.initialize()
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.tmp == nil {
+		if err := f.initialize(); err != nil {
+			f.err = err
+			return 0, err
+		}
 	}
-	n, err := f.w.Write(p)
 
-	// Trap io.EOF and reset reader (so that the reader is always ready)
-	if err == io.EOF {
-		f.isWritten = true
+	n, err := f.tmp.Write(p)
+	f.hash.Write(p[:n])
+	if err != nil {
+		f.err = err
 	}
 	return n, err
 }
 
-// Close is a wrapper for an io.Closer
+// ReadFrom replaces the file with the contents of r, but only if all of r
+// could be read and written
+func (f *File) ReadFrom(r io.Reader) (int64, error) {
+	if err := f.initialize(); err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(io.MultiWriter(f.tmp, f.hash), r)
+	if err != nil {
+		f.abort()
+		return n, err
+	}
+
+	if s, ok := r.(internal.Secret); ok {
+		f.secret = s.IsSecret()
+	}
+	return n, f.commit()
+}
+
+// Close is a wrapper for an io.Closer, which moves any written content into
+// place. If a previous Write failed, the written content is discarded.
 func (f *File) Close() error {
-	f.isWritten = true
-	return f.w.Close()
+	if f.err != nil {
+		err := f.err
+		f.abort()
+		return err
+	}
+	if f.tmp == nil {
+		return nil
+	}
+	return f.commit()
 }
 
-func (f *File) initialize() {
+// Changed reports whether the last write replaced the content of the file
+func (f *File) Changed() bool {
+	return f.changed
+}
+
+// filename is the full path of the file
+func (f *File) filename() string {
+	return filepath.Join(f.Path, f.Name)
+}
+
+func (f *File) initialize() error {
+	// Discard anything left over from a previous write
+	if f.tmp != nil {
+		f.abort()
+	}
+
 	// Ensure path exists
-	info, statErr := os.Stat(f.Path)
+	dir, name := filepath.Split(f.filename())
+	info, statErr := os.Stat(dir)
 	if statErr != nil || !info.IsDir() {
-		os.MkdirAll(f.Path, 0755)
+		if err := os.MkdirAll(dir, f.DirMode); err != nil {
+			return err
+		}
+
+		// MkdirAll is subject to the umask, so set the mode explicitly
+		if err := os.Chmod(dir, f.DirMode); err != nil {
+			return err
+		}
+		if err := os.Lchown(dir, f.UID, f.GID); err != nil {
+			return err
+		}
 	}
 
-	// Create File
-	lf, openErr := os.OpenFile(filepath.Join(f.Path, f.Name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if openErr != nil {
-		fmt.Println("Error creating or opening file")
+	// Create temporary file next to the file, so that it can be renamed
+	tmp, err := ioutil.TempFile(dir, "."+name+".*.tmp")
+	if err != nil {
+		return err
 	}
 
-	f.w = lf
-	f.isWritten = false
+	f.tmp = tmp
+	f.hash = sha256.New()
+	f.err = nil
+	f.changed = false
+	return nil
+}
+
+// mode is the mode the file is written with
+func (f *File) mode() os.FileMode {
+	switch {
+	case f.Mode != 0:
+		return f.Mode
+	case f.secret:
+		return DefaultSecretMode
+	default:
+		return DefaultMode
+	}
+}
+
+// commit flushes the temporary file to disk and renames it over the file.
+// The mode and owner are set before the rename, so the file never appears
+// with different permissions.
+func (f *File) commit() error {
+	if f.unchanged() {
+		f.abort()
+		f.changed = false
+		return f.chmod()
+	}
+
+	tmp := f.tmp
+	f.tmp = nil
+
+	err := tmp.Chmod(f.mode())
+	if err == nil && (f.UID != -1 || f.GID != -1) {
+		err = tmp.Chown(f.UID, f.GID)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.filename())
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	f.changed = true
+
+	// Persist the rename itself
+	if dir, dirErr := os.Open(filepath.Dir(f.filename())); dirErr == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// unchanged reports whether the existing file has the same content as the
+// temporary file
+func (f *File) unchanged() bool {
+	info, err := os.Stat(f.filename())
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	written, err := f.tmp.Seek(0, io.SeekCurrent)
+	if err != nil || written != info.Size() {
+		return false
+	}
+
+	existing, err := os.Open(f.filename())
+	if err != nil {
+		return false
+	}
+	defer existing.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, existing); err != nil {
+		return false
+	}
+	return bytes.Equal(h.Sum(nil), f.hash.Sum(nil))
+}
+
+// chmod applies the mode and owner to the existing file, when the content is
+// not replaced
+func (f *File) chmod() error {
+	info, err := os.Stat(f.filename())
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm() != f.mode() {
+		if err := os.Chmod(f.filename(), f.mode()); err != nil {
+			return err
+		}
+	}
+	if f.UID != -1 || f.GID != -1 {
+		return os.Chown(f.filename(), f.UID, f.GID)
+	}
+	return nil
+}
+
+// abort discards the temporary file
+func (f *File) abort() {
+	if f.tmp != nil {
+		f.tmp.Close()
+		os.Remove(f.tmp.Name())
+	}
+	f.tmp = nil
+	f.err = nil
 }
@@ -0,0 +1,180 @@
+package local
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// secretReader is a reader of a secret source
+type secretReader struct {
+	io.Reader
+}
+
+func (r secretReader) IsSecret() bool {
+	return true
+}
+
+// failingReader returns an error after its content
+type failingReader struct {
+	io.Reader
+}
+
+func (r failingReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// assertNoTempFiles checks that no temporary files are left in dir
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	matches, _ := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+	if len(matches) > 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestFileReadFrom(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sub")
+	f := NewFile(dir, "seed")
+
+	n, err := f.ReadFrom(strings.NewReader("hello"))
+	if err != nil || n != 5 {
+		t.Fatalf("ReadFrom() = %d, %v, want 5, nil", n, err)
+	}
+	if !f.Changed() {
+		t.Error("Changed() = false for a new file")
+	}
+	name := filepath.Join(dir, "seed")
+	if got := readFile(t, name); got != "hello" {
+		t.Errorf("content = %q, want %q", got, "hello")
+	}
+	if info, _ := os.Stat(name); info.Mode().Perm() != DefaultMode {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), DefaultMode)
+	}
+	if info, _ := os.Stat(dir); info.Mode().Perm() != DefaultDirMode {
+		t.Errorf("directory mode = %v, want %v", info.Mode().Perm(), DefaultDirMode)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestFileReadFromFailure(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "seed")
+	if err := ioutil.WriteFile(name, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFile(dir, "seed")
+	if _, err := f.ReadFrom(failingReader{strings.NewReader("partial")}); err == nil {
+		t.Fatal("ReadFrom() succeeded, want an error")
+	}
+	if got := readFile(t, name); got != "old" {
+		t.Errorf("content = %q, want the old content", got)
+	}
+	if f.Changed() {
+		t.Error("Changed() = true after a failed write")
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestFileUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "seed")
+	if err := ioutil.WriteFile(name, []byte("same"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(name, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFile(dir, "seed", WithMode(0640))
+	if _, err := f.ReadFrom(strings.NewReader("same")); err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+	if f.Changed() {
+		t.Error("Changed() = true for identical content")
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("mtime = %v, want %v", info.ModTime(), past)
+	}
+	// The mode is still applied to the unchanged file
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0640))
+	}
+	assertNoTempFiles(t, dir)
+
+	if _, err := f.ReadFrom(strings.NewReader("different")); err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+	if !f.Changed() {
+		t.Error("Changed() = false for different content")
+	}
+}
+
+func TestFileSecretMode(t *testing.T) {
+	dir := t.TempDir()
+
+	f := NewFile(dir, "secret")
+	if _, err := f.ReadFrom(secretReader{strings.NewReader("password")}); err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "secret")); info.Mode().Perm() != DefaultSecretMode {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), DefaultSecretMode)
+	}
+
+	// A configured mode wins over the default of secrets
+	f = NewFile(dir, "shared", WithMode(0640))
+	if _, err := f.ReadFrom(secretReader{strings.NewReader("password")}); err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "shared")); info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0640))
+	}
+}
+
+func TestFileWriteClose(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "seed")
+
+	f := NewFile(dir, "seed")
+	if _, err := io.WriteString(f, "hello "); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(f, "world"); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing is visible until the file is closed
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("file exists before Close: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := readFile(t, name); got != "hello world" {
+		t.Errorf("content = %q, want %q", got, "hello world")
+	}
+	assertNoTempFiles(t, dir)
+}
@@ -0,0 +1,154 @@
+package local
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+// ManifestName is the name of the file listing the entries written to a
+// directory, so that entries removed from the tree can be removed as well
+const ManifestName = ".seeder-manifest"
+
+// WriteTree writes every entry to its own file in the directory of the File,
+// named by the name of the entry. Each file is replaced atomically and with the
+// mode and owner of the File. With prune, files written by a previous WriteTree
+// whose entries are no longer in the tree are removed, but only if every entry
+// could be written. Other files in the directory are left alone.
+func (f *File) WriteTree(entries []internal.Entry, prune bool) (int64, error) {
+	f.changed = false
+	dir := f.filename()
+
+	var written int64
+	var err error
+	names := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		name, nameErr := entryName(e.Name)
+		if nameErr != nil {
+			if err == nil {
+				err = nameErr
+			}
+			continue
+		}
+
+		child := f.child(dir, name)
+		child.secret = e.Secret
+		n, writeErr := child.ReadFrom(bytes.NewReader(e.Value))
+		written += n
+		if writeErr != nil {
+			if err == nil {
+				err = fmt.Errorf("unable to write %s: %w", name, writeErr)
+			}
+			continue
+		}
+		names[name] = true
+		f.changed = f.changed || child.changed
+	}
+
+	previous, manifestErr := readManifest(dir)
+	if manifestErr != nil && err == nil {
+		err = manifestErr
+	}
+	for name := range previous {
+		if names[name] {
+			continue
+		}
+		// Keep track of old entries until they can be removed safely
+		if err != nil || !prune {
+			names[name] = true
+			continue
+		}
+		if rmErr := os.Remove(filepath.Join(dir, name)); rmErr != nil && !os.IsNotExist(rmErr) {
+			err = rmErr
+			names[name] = true
+			continue
+		}
+		removeEmptyDirs(dir, filepath.Dir(name))
+		f.changed = true
+	}
+
+	if manifestErr := f.writeManifest(dir, names); manifestErr != nil && err == nil {
+		err = manifestErr
+	}
+	return written, err
+}
+
+// child is a File for an entry, with the same options as f
+func (f *File) child(dir, name string) *File {
+	return &File{
+		Path:    dir,
+		Name:    name,
+		Mode:    f.Mode,
+		DirMode: f.DirMode,
+		UID:     f.UID,
+		GID:     f.GID,
+	}
+}
+
+// entryName checks that the name of an entry stays within the directory
+func entryName(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if name == "" || filepath.IsAbs(clean) || clean == "." || clean == ".." ||
+		strings.HasPrefix(clean, ".."+string(filepath.Separator)) || clean == ManifestName {
+		return "", fmt.Errorf("invalid entry name %q", name)
+	}
+	return clean, nil
+}
+
+// readManifest returns the names of the entries last written to dir
+func readManifest(dir string) (map[string]bool, error) {
+	file, err := os.Open(filepath.Join(dir, ManifestName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	names := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Ignore anything that could point outside of the directory
+		if name, err := entryName(scanner.Text()); err == nil {
+			names[name] = true
+		}
+	}
+	return names, scanner.Err()
+}
+
+// writeManifest records the names of the entries written to dir
+func (f *File) writeManifest(dir string, names map[string]bool) error {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, filepath.ToSlash(name))
+	}
+	sort.Strings(sorted)
+
+	var buf bytes.Buffer
+	for _, name := range sorted {
+		buf.WriteString(name + "\n")
+	}
+
+	manifest := f.child(dir, ManifestName)
+	manifest.Mode = DefaultMode
+	_, err := manifest.ReadFrom(&buf)
+	return err
+}
+
+// removeEmptyDirs removes rel and its parents within dir, as long as they are
+// empty
+func removeEmptyDirs(dir, rel string) {
+	for rel != "." && rel != string(filepath.Separator) {
+		if os.Remove(filepath.Join(dir, rel)) != nil {
+			return
+		}
+		rel = filepath.Dir(rel)
+	}
+}
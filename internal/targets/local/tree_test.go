@@ -0,0 +1,124 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buzzsurfr/seeder/internal"
+)
+
+func TestWriteTree(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFile(dir, "")
+
+	entries := []internal.Entry{
+		{Name: "log-level", Value: []byte("debug")},
+		{Name: "db/password", Value: []byte("secret"), Secret: true},
+	}
+	if _, err := f.WriteTree(entries, true); err != nil {
+		t.Fatalf("WriteTree() error = %v", err)
+	}
+	if !f.Changed() {
+		t.Error("Changed() = false for new entries")
+	}
+	if got := readFile(t, filepath.Join(dir, "db", "password")); got != "secret" {
+		t.Errorf("db/password = %q, want %q", got, "secret")
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "log-level")); info.Mode().Perm() != DefaultMode {
+		t.Errorf("mode of log-level = %v, want %v", info.Mode().Perm(), DefaultMode)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "db", "password")); info.Mode().Perm() != DefaultSecretMode {
+		t.Errorf("mode of db/password = %v, want %v", info.Mode().Perm(), DefaultSecretMode)
+	}
+	if got := readFile(t, filepath.Join(dir, ManifestName)); got != "db/password\nlog-level\n" {
+		t.Errorf("manifest = %q", got)
+	}
+
+	// Writing the same entries again changes nothing
+	if _, err := f.WriteTree(entries, true); err != nil {
+		t.Fatalf("WriteTree() error = %v", err)
+	}
+	if f.Changed() {
+		t.Error("Changed() = true for the same entries")
+	}
+}
+
+func TestWriteTreePrune(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFile(dir, "")
+	other := filepath.Join(dir, "other")
+	if err := ioutil.WriteFile(other, []byte("not ours"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []internal.Entry{
+		{Name: "a", Value: []byte("a")},
+		{Name: "nested/b", Value: []byte("b")},
+	}
+	if _, err := f.WriteTree(entries, true); err != nil {
+		t.Fatalf("WriteTree() error = %v", err)
+	}
+
+	// Without prune, old entries stay, and are still removed later
+	if _, err := f.WriteTree(entries[:1], false); err != nil {
+		t.Fatalf("WriteTree() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nested", "b")); err != nil {
+		t.Errorf("nested/b removed without prune: %v", err)
+	}
+
+	// A failed write keeps old entries
+	failed := append(entries[:1:1], internal.Entry{Name: "../escape", Value: []byte("x")})
+	if _, err := f.WriteTree(failed, true); err == nil {
+		t.Fatal("WriteTree() succeeded with an invalid entry name")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nested", "b")); err != nil {
+		t.Errorf("nested/b removed after a failed write: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "..", "escape")); !os.IsNotExist(err) {
+		t.Errorf("entry written outside of the directory: %v", err)
+	}
+
+	if _, err := f.WriteTree(entries[:1], true); err != nil {
+		t.Fatalf("WriteTree() error = %v", err)
+	}
+	if !f.Changed() {
+		t.Error("Changed() = false after removing an entry")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nested")); !os.IsNotExist(err) {
+		t.Errorf("nested not removed: %v", err)
+	}
+	if got := readFile(t, other); got != "not ours" {
+		t.Errorf("other file = %q, want it left alone", got)
+	}
+	if got := readFile(t, filepath.Join(dir, ManifestName)); got != "a\n" {
+		t.Errorf("manifest = %q, want %q", got, "a\n")
+	}
+}
+
+func TestEntryName(t *testing.T) {
+	valid := map[string]string{
+		"a":         "a",
+		"a/b":       filepath.Join("a", "b"),
+		"a/./b":     filepath.Join("a", "b"),
+		"a/../b":    "b",
+		"..a":       "..a",
+		"a/b/":      filepath.Join("a", "b"),
+		"dir/.keep": filepath.Join("dir", ".keep"),
+	}
+	for name, want := range valid {
+		got, err := entryName(name)
+		if err != nil || got != want {
+			t.Errorf("entryName(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+
+	invalid := []string{"", ".", "..", "../a", "a/../..", "a/../../b", "/etc/passwd", ManifestName}
+	for _, name := range invalid {
+		if got, err := entryName(name); err == nil {
+			t.Errorf("entryName(%q) = %q, want an error", name, got)
+		}
+	}
+}